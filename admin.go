@@ -0,0 +1,265 @@
+package picosql
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// adminDialect is implemented by the dialects whose databases, users and
+// grants picosql can manage. Names are validated by the callers.
+type adminDialect interface {
+	createDatabaseSQL(db, charset, collation string) string
+	dropDatabaseSQL(db string) string
+	// accountExistsQuery selects a row when user exists for host.
+	accountExistsQuery(user, host string) (string, []interface{})
+	createUserSQL(user, host, password string) string
+	setPasswordSQL(user, host, password string) string
+	dropUserSQL(user, host string) string
+	// grantSQL grants privileges on db, or on one of its tables when table
+	// is not empty.
+	grantSQL(privileges []string, db, table, user, host string, grantOption bool) string
+	revokeSQL(privileges []string, db, table, user, host string) string
+	revokeAllSQL(db, user, host string) string
+	// readOnlySQL grants user read access to the tables of db.
+	readOnlySQL(db, user, host string) []string
+	// grantsQuery selects the grants of user into Grant.
+	grantsQuery(user, host string) (string, []interface{})
+}
+
+func (m *Sql) admin() (adminDialect, error) {
+	a, ok := m.Dialect().(adminDialect)
+	if !ok {
+		return nil, notSupported(m.Dialect(), "User and database management")
+	}
+	return a, nil
+}
+
+// accountExists is UserExists for one host of user on MySQL.
+func (m *Sql) accountExists(a adminDialect, user, host string) (bool, error) {
+	q, args := a.accountExistsQuery(user, host)
+	rows, err := m.Query(q, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+var privilegeName = regexp.MustCompile(`^[A-Za-z]+( [A-Za-z]+)*$`)
+
+// validatePrivileges accepts privilege names such as SELECT or ALL
+// PRIVILEGES and upper cases them.
+func validatePrivileges(privileges []string) ([]string, error) {
+	if len(privileges) == 0 {
+		return nil, fmt.Errorf("No privileges")
+	}
+
+	valid := make([]string, len(privileges))
+	for i, p := range privileges {
+		p = strings.Join(strings.Fields(p), " ")
+		if !privilegeName.MatchString(p) {
+			return nil, fmt.Errorf("Invalid privilege %q", p)
+		}
+		valid[i] = strings.ToUpper(p)
+	}
+	return valid, nil
+}
+
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generatePassword returns a random password of n characters that needs no
+// escaping in a DSN.
+func generatePassword(n int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		x, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordAlphabet[x.Int64()]
+	}
+	return string(b), nil
+}
+
+// MySQL accounts are a user name and the host it may connect from.
+
+func (d mysqlDialect) account(user, host string) string {
+	if len(host) == 0 {
+		host = "%"
+	}
+	return d.QuoteLiteral(user) + "@" + d.QuoteLiteral(host)
+}
+
+func (d mysqlDialect) createDatabaseSQL(db, charset, collation string) string {
+	s := "CREATE DATABASE IF NOT EXISTS " + d.QuoteIdent(db)
+	if len(charset) > 0 {
+		s += " CHARACTER SET " + charset
+	}
+	if len(collation) > 0 {
+		s += " COLLATE " + collation
+	}
+	return s
+}
+
+func (d mysqlDialect) dropDatabaseSQL(db string) string {
+	return "DROP DATABASE IF EXISTS " + d.QuoteIdent(db)
+}
+
+func (d mysqlDialect) accountExistsQuery(user, host string) (string, []interface{}) {
+	if len(host) == 0 {
+		host = "%"
+	}
+	return "SELECT 1 FROM mysql.user WHERE User = ? AND Host = ?", []interface{}{user, host}
+}
+
+func (d mysqlDialect) createUserSQL(user, host, password string) string {
+	return "CREATE USER IF NOT EXISTS " + d.account(user, host) + " IDENTIFIED BY " + d.QuoteLiteral(password)
+}
+
+func (d mysqlDialect) setPasswordSQL(user, host, password string) string {
+	return "ALTER USER " + d.account(user, host) + " IDENTIFIED BY " + d.QuoteLiteral(password)
+}
+
+func (d mysqlDialect) dropUserSQL(user, host string) string {
+	return "DROP USER IF EXISTS " + d.account(user, host)
+}
+
+func (d mysqlDialect) grantOn(db, table string) string {
+	if len(table) > 0 {
+		return quoteQualified(d, db, table)
+	}
+	return d.QuoteIdent(db) + ".*"
+}
+
+func (d mysqlDialect) grantSQL(privileges []string, db, table, user, host string, grantOption bool) string {
+	s := "GRANT " + strings.Join(privileges, ", ") + " ON " + d.grantOn(db, table) + " TO " + d.account(user, host)
+	if grantOption {
+		s += " WITH GRANT OPTION"
+	}
+	return s
+}
+
+func (d mysqlDialect) revokeSQL(privileges []string, db, table, user, host string) string {
+	return "REVOKE " + strings.Join(privileges, ", ") + " ON " + d.grantOn(db, table) + " FROM " + d.account(user, host)
+}
+
+func (d mysqlDialect) revokeAllSQL(db, user, host string) string {
+	return "REVOKE ALL PRIVILEGES, GRANT OPTION ON " + d.QuoteIdent(db) + ".* FROM " + d.account(user, host)
+}
+
+func (d mysqlDialect) readOnlySQL(db, user, host string) []string {
+	return []string{d.grantSQL([]string{"SELECT", "SHOW VIEW"}, db, "", user, host, false)}
+}
+
+func (d mysqlDialect) grantsQuery(user, host string) (string, []interface{}) {
+	grantee := d.account(user, host)
+	return `SELECT '' AS db, '' AS table_name, PRIVILEGE_TYPE AS privilege, IS_GRANTABLE = 'YES' AS grant_option
+		FROM information_schema.USER_PRIVILEGES WHERE GRANTEE = ?
+		UNION ALL
+		SELECT TABLE_SCHEMA, '', PRIVILEGE_TYPE, IS_GRANTABLE = 'YES' FROM information_schema.SCHEMA_PRIVILEGES WHERE GRANTEE = ?
+		UNION ALL
+		SELECT TABLE_SCHEMA, TABLE_NAME, PRIVILEGE_TYPE, IS_GRANTABLE = 'YES' FROM information_schema.TABLE_PRIVILEGES WHERE GRANTEE = ?
+		ORDER BY 1, 2, 3`, []interface{}{grantee, grantee, grantee}
+}
+
+// PostgreSQL roles have no host; pg_hba.conf restricts where they connect
+// from. Table grants apply to the database of the connection.
+
+func (d postgresDialect) createDatabaseSQL(db, charset, collation string) string {
+	s := "CREATE DATABASE " + d.QuoteIdent(db)
+	if len(charset) > 0 || len(collation) > 0 {
+		s += " TEMPLATE template0"
+	}
+	if len(charset) > 0 {
+		s += " ENCODING " + d.QuoteLiteral(charset)
+	}
+	if len(collation) > 0 {
+		s += " LC_COLLATE " + d.QuoteLiteral(collation) + " LC_CTYPE " + d.QuoteLiteral(collation)
+	}
+	return s
+}
+
+// databaseOwnerSQL hands db to user, so the user can create tables in its
+// public schema.
+func (d postgresDialect) databaseOwnerSQL(db, user string) string {
+	return "ALTER DATABASE " + d.QuoteIdent(db) + " OWNER TO " + d.QuoteIdent(user)
+}
+
+// reassignOwnedSQL hands what user owns to the connected role and drops its
+// remaining privileges, so the role can be dropped. Objects in databases other
+// than the connected one are not covered.
+func (d postgresDialect) reassignOwnedSQL(user string) []string {
+	role := d.QuoteIdent(user)
+	return []string{
+		"REASSIGN OWNED BY " + role + " TO CURRENT_USER",
+		"DROP OWNED BY " + role,
+	}
+}
+
+func (d postgresDialect) dropDatabaseSQL(db string) string {
+	return "DROP DATABASE IF EXISTS " + d.QuoteIdent(db)
+}
+
+func (d postgresDialect) accountExistsQuery(user, host string) (string, []interface{}) {
+	return "SELECT 1 FROM pg_roles WHERE rolname = $1", []interface{}{user}
+}
+
+func (d postgresDialect) createUserSQL(user, host, password string) string {
+	return "CREATE ROLE " + d.QuoteIdent(user) + " LOGIN PASSWORD " + d.QuoteLiteral(password)
+}
+
+func (d postgresDialect) setPasswordSQL(user, host, password string) string {
+	return "ALTER ROLE " + d.QuoteIdent(user) + " WITH PASSWORD " + d.QuoteLiteral(password)
+}
+
+func (d postgresDialect) dropUserSQL(user, host string) string {
+	return "DROP ROLE IF EXISTS " + d.QuoteIdent(user)
+}
+
+func (d postgresDialect) grantOn(db, table string) string {
+	if len(table) > 0 {
+		return "TABLE " + d.QuoteIdent(table)
+	}
+	return "DATABASE " + d.QuoteIdent(db)
+}
+
+func (d postgresDialect) grantSQL(privileges []string, db, table, user, host string, grantOption bool) string {
+	s := "GRANT " + strings.Join(privileges, ", ") + " ON " + d.grantOn(db, table) + " TO " + d.QuoteIdent(user)
+	if grantOption {
+		s += " WITH GRANT OPTION"
+	}
+	return s
+}
+
+func (d postgresDialect) revokeSQL(privileges []string, db, table, user, host string) string {
+	return "REVOKE " + strings.Join(privileges, ", ") + " ON " + d.grantOn(db, table) + " FROM " + d.QuoteIdent(user)
+}
+
+func (d postgresDialect) revokeAllSQL(db, user, host string) string {
+	return "REVOKE ALL PRIVILEGES ON DATABASE " + d.QuoteIdent(db) + " FROM " + d.QuoteIdent(user)
+}
+
+// readOnlySQL covers the public schema, including the tables created later.
+// The connection has to be to db, as schemas belong to a database.
+func (d postgresDialect) readOnlySQL(db, user, host string) []string {
+	role := d.QuoteIdent(user)
+	return []string{
+		"GRANT CONNECT ON DATABASE " + d.QuoteIdent(db) + " TO " + role,
+		"GRANT USAGE ON SCHEMA public TO " + role,
+		"GRANT SELECT ON ALL TABLES IN SCHEMA public TO " + role,
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT ON TABLES TO " + role,
+	}
+}
+
+func (d postgresDialect) grantsQuery(user, host string) (string, []interface{}) {
+	return `SELECT d.datname::text AS db, ''::text AS table_name, a.privilege_type::text AS privilege, a.is_grantable AS grant_option
+		FROM pg_database d CROSS JOIN LATERAL aclexplode(d.datacl) a JOIN pg_roles r ON r.oid = a.grantee
+		WHERE r.rolname = $1
+		UNION ALL
+		SELECT table_catalog, table_name, privilege_type, is_grantable = 'YES' FROM information_schema.table_privileges WHERE grantee = $1
+		ORDER BY 1, 2, 3`, []interface{}{user}
+}
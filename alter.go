@@ -0,0 +1,540 @@
+package picosql
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type AlterOptions struct {
+	// DryRun prints the statements instead of running them.
+	DryRun bool
+	// Out receives the statements, os.Stdout in dry-run mode when nil.
+	Out io.Writer
+	// DropColumns drops live columns missing from the desired structure.
+	// Without it they are left alone.
+	DropColumns bool
+	// DropIndexes drops live indexes, including the primary key, missing
+	// from the desired structure.
+	DropIndexes bool
+}
+
+// alterWriter is implemented by the dialects that can plan ALTER TABLE
+// statements.
+type alterWriter interface {
+	columnClause(c *ColumnDefinition) string
+	addColumnSQL(table string, c *ColumnDefinition) string
+	dropColumnSQL(table, column string) string
+	modifyColumnSQL(table string, from, to *ColumnDefinition) ([]string, error)
+	createIndexSQL(table string, idx *IndexDefinition, ifNotExists bool) (string, error)
+	dropIndexSQL(table string, idx *IndexDefinition) (string, error)
+}
+
+func alterWriterFor(d Dialect) (alterWriter, error) {
+	aw, ok := d.(alterWriter)
+	if !ok {
+		return nil, notSupported(d, "ALTER TABLE planning")
+	}
+	return aw, nil
+}
+
+// PlanAlter returns the statements that turn the live table into the desired
+// one, in the order they must run: dropped indexes, dropped, added and
+// modified columns, then new indexes.
+func PlanAlter(d Dialect, desired, live *TableStructure, opts AlterOptions) ([]string, error) {
+	aw, err := alterWriterFor(d)
+	if err != nil {
+		return nil, err
+	}
+
+	table := quoteQualified(d, desired.DatabaseName, desired.TableName)
+	var drops, columns, creates []string
+
+	liveColumns := make(map[string]*ColumnDefinition)
+	for _, c := range live.Columns {
+		liveColumns[c.ColumnName] = c
+	}
+
+	desiredColumns := make(map[string]*ColumnDefinition)
+	for _, c := range desired.Columns {
+		desiredColumns[c.ColumnName] = c
+	}
+
+	if opts.DropColumns {
+		for _, c := range live.Columns {
+			if _, ok := desiredColumns[c.ColumnName]; !ok {
+				columns = append(columns, aw.dropColumnSQL(table, c.ColumnName))
+			}
+		}
+	}
+
+	for _, c := range desired.Columns {
+		lc, ok := liveColumns[c.ColumnName]
+		if !ok {
+			columns = append(columns, aw.addColumnSQL(table, c))
+			continue
+		}
+
+		if sameColumn(lc, c) {
+			continue
+		}
+
+		stmts, err := aw.modifyColumnSQL(table, lc, c)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, stmts...)
+	}
+
+	liveIndexes := make(map[string]*IndexDefinition)
+	for _, idx := range live.Indexes {
+		liveIndexes[indexKey(idx)] = idx
+	}
+
+	desiredIndexes := make(map[string]*IndexDefinition)
+	for _, idx := range withPrimaryKey(desired) {
+		desiredIndexes[indexKey(idx)] = idx
+	}
+
+	for _, idx := range live.Indexes {
+		di, ok := desiredIndexes[indexKey(idx)]
+		if ok && indexSignature(di) == indexSignature(idx) {
+			continue
+		}
+		if !ok && !opts.DropIndexes {
+			continue
+		}
+
+		s, err := aw.dropIndexSQL(table, idx)
+		if err != nil {
+			return nil, err
+		}
+		drops = append(drops, s)
+	}
+
+	for _, idx := range withPrimaryKey(desired) {
+		li, ok := liveIndexes[indexKey(idx)]
+		if ok && indexSignature(li) == indexSignature(idx) {
+			continue
+		}
+
+		s, err := aw.createIndexSQL(table, idx, false)
+		if err != nil {
+			return nil, err
+		}
+		creates = append(creates, s)
+	}
+
+	return append(append(drops, columns...), creates...), nil
+}
+
+// AlterTable compares desired with the live table of the same name and runs,
+// or in dry-run mode prints, the statements returned by PlanAlter.
+func (m *Sql) AlterTable(desired *TableStructure, opts AlterOptions) ([]string, error) {
+	live, err := m.IntrospectTable(desired.DatabaseName, desired.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, err := PlanAlter(m.Dialect(), desired, live, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := opts.Out
+	if out == nil && opts.DryRun {
+		out = os.Stdout
+	}
+
+	for _, s := range stmts {
+		if out != nil {
+			fmt.Fprintln(out, s+";")
+		}
+		if opts.DryRun {
+			continue
+		}
+		if _, err := m.Exec(s); err != nil {
+			return stmts, err
+		}
+	}
+	return stmts, nil
+}
+
+// withPrimaryKey returns the indexes of t including its PrimaryKey, which may
+// be given without a matching primary index.
+func withPrimaryKey(t *TableStructure) []*IndexDefinition {
+	indexes := t.Indexes
+	if len(t.PrimaryKey) == 0 {
+		return indexes
+	}
+
+	for _, idx := range indexes {
+		if idx.Primary {
+			return indexes
+		}
+	}
+
+	pk := &IndexDefinition{Name: "PRIMARY", Unique: true, Primary: true}
+	for _, c := range t.PrimaryKey {
+		pk.Columns = append(pk.Columns, IndexColumn{Name: c})
+	}
+	return append([]*IndexDefinition{pk}, indexes...)
+}
+
+// indexKey matches primary keys whatever the engine names them.
+func indexKey(idx *IndexDefinition) string {
+	if idx.Primary {
+		return "\x00primary"
+	}
+	return idx.Name
+}
+
+func sameColumn(live, desired *ColumnDefinition) bool {
+	if normalizeType(columnTypeOf(live)) != normalizeType(columnTypeOf(desired)) {
+		return false
+	}
+
+	if live.IsNullable != desired.IsNullable || live.AutoIncrement != desired.AutoIncrement {
+		return false
+	}
+
+	if (live.Default == nil) != (desired.Default == nil) {
+		return false
+	}
+
+	if live.Default != nil && normalizeDefault(*live.Default) != normalizeDefault(*desired.Default) {
+		return false
+	}
+
+	if len(desired.Collation) > 0 && !strings.EqualFold(live.Collation, desired.Collation) {
+		return false
+	}
+
+	if len(desired.Comment) > 0 && live.Comment != desired.Comment {
+		return false
+	}
+	return true
+}
+
+func columnTypeOf(c *ColumnDefinition) string {
+	if len(c.ColumnType) > 0 {
+		return c.ColumnType
+	}
+	return c.DataType
+}
+
+var (
+	integerDisplayWidth = regexp.MustCompile(`^(bigint|int|mediumint|smallint)\(\d+\)`)
+	typeAliases         = map[string]string{
+		"character varying":           "varchar",
+		"character":                   "char",
+		"integer":                     "int",
+		"int4":                        "int",
+		"int8":                        "bigint",
+		"int2":                        "smallint",
+		"bool":                        "boolean",
+		"float8":                      "double",
+		"double precision":            "double",
+		"float4":                      "real",
+		"numeric":                     "decimal",
+		"timestamp without time zone": "timestamp",
+		"timestamp with time zone":    "timestamptz",
+	}
+)
+
+// normalizeType makes types reported by different engines, or written by
+// hand, comparable: "INT(11)" and "integer" both become "int".
+func normalizeType(t string) string {
+	t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+	t = integerDisplayWidth.ReplaceAllString(t, "$1")
+
+	base, rest := t, ""
+	if i := strings.Index(t, "("); i >= 0 {
+		base, rest = strings.TrimSpace(t[:i]), t[i:]
+	}
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	return base + strings.ReplaceAll(rest, " ", "")
+}
+
+// normalizeDefault strips the quoting and casts engines add to defaults, so
+// that x, 'x' and 'x'::character varying compare equal.
+func normalizeDefault(d string) string {
+	d = strings.TrimSpace(d)
+	if i := strings.Index(d, "::"); i > 0 && !strings.Contains(d[i:], "'") {
+		d = d[:i]
+	}
+	for len(d) >= 2 && d[0] == '(' && d[len(d)-1] == ')' {
+		d = strings.TrimSpace(d[1 : len(d)-1])
+	}
+	if len(d) >= 2 && d[0] == '\'' && d[len(d)-1] == '\'' {
+		d = strings.ReplaceAll(d[1:len(d)-1], "''", "'")
+	}
+	return strings.ToLower(d)
+}
+
+// Column and index clauses shared by the dialects.
+
+func columnClause(d Dialect, c *ColumnDefinition, autoIncrement string) string {
+	s := d.QuoteIdent(c.ColumnName) + " " + columnTypeOf(c)
+	if c.IsNullable {
+		s += " NULL"
+	} else {
+		s += " NOT NULL"
+	}
+	if c.Default != nil {
+		s += " DEFAULT " + *c.Default
+	}
+	if c.AutoIncrement && len(autoIncrement) > 0 {
+		s += " " + autoIncrement
+	}
+	return s
+}
+
+func indexColumns(d Dialect, idx *IndexDefinition, prefixes bool) string {
+	columns := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		columns[i] = d.QuoteIdent(c.Name)
+		if prefixes && c.Length > 0 {
+			columns[i] += fmt.Sprintf("(%d)", c.Length)
+		}
+		if c.Desc {
+			columns[i] += " DESC"
+		}
+	}
+	return "(" + strings.Join(columns, ", ") + ")"
+}
+
+func createIndexStatement(d Dialect, table, name string, idx *IndexDefinition, prefixes, ifNotExists bool) string {
+	s := "CREATE "
+	if idx.Unique {
+		s += "UNIQUE "
+	}
+	s += "INDEX "
+	if ifNotExists {
+		s += "IF NOT EXISTS "
+	}
+	s += name + " ON " + table + " " + indexColumns(d, idx, prefixes)
+
+	if len(idx.Include) > 0 {
+		include := make([]string, len(idx.Include))
+		for i, c := range idx.Include {
+			include[i] = d.QuoteIdent(c)
+		}
+		s += " INCLUDE (" + strings.Join(include, ", ") + ")"
+	}
+	return s
+}
+
+// MySQL
+
+func (d mysqlDialect) columnClause(c *ColumnDefinition) string {
+	s := columnClause(d, c, "AUTO_INCREMENT")
+	if len(c.Collation) > 0 {
+		s += " COLLATE " + c.Collation
+	}
+	if len(c.Comment) > 0 {
+		s += " COMMENT '" + strings.ReplaceAll(c.Comment, "'", "''") + "'"
+	}
+	return s
+}
+
+func (d mysqlDialect) addColumnSQL(table string, c *ColumnDefinition) string {
+	return "ALTER TABLE " + table + " ADD COLUMN " + d.columnClause(c)
+}
+
+func (d mysqlDialect) dropColumnSQL(table, column string) string {
+	return "ALTER TABLE " + table + " DROP COLUMN " + d.QuoteIdent(column)
+}
+
+func (d mysqlDialect) modifyColumnSQL(table string, from, to *ColumnDefinition) ([]string, error) {
+	return []string{"ALTER TABLE " + table + " MODIFY COLUMN " + d.columnClause(to)}, nil
+}
+
+func (d mysqlDialect) createIndexSQL(table string, idx *IndexDefinition, ifNotExists bool) (string, error) {
+	if ifNotExists {
+		return "", notSupported(d, "CREATE INDEX IF NOT EXISTS")
+	}
+	if len(idx.Include) > 0 {
+		return "", notSupported(d, "INCLUDE columns")
+	}
+	if idx.Primary {
+		return "ALTER TABLE " + table + " ADD PRIMARY KEY " + indexColumns(d, idx, true), nil
+	}
+	return createIndexStatement(d, table, d.QuoteIdent(idx.Name), idx, true, false), nil
+}
+
+func (d mysqlDialect) dropIndexSQL(table string, idx *IndexDefinition) (string, error) {
+	if idx.Primary {
+		return "ALTER TABLE " + table + " DROP PRIMARY KEY", nil
+	}
+	return "DROP INDEX " + d.QuoteIdent(idx.Name) + " ON " + table, nil
+}
+
+// PostgreSQL
+
+func (d postgresDialect) columnClause(c *ColumnDefinition) string {
+	return columnClause(d, c, "GENERATED BY DEFAULT AS IDENTITY")
+}
+
+func (d postgresDialect) addColumnSQL(table string, c *ColumnDefinition) string {
+	return "ALTER TABLE " + table + " ADD COLUMN " + d.columnClause(c)
+}
+
+func (d postgresDialect) dropColumnSQL(table, column string) string {
+	return "ALTER TABLE " + table + " DROP COLUMN " + d.QuoteIdent(column)
+}
+
+func (d postgresDialect) modifyColumnSQL(table string, from, to *ColumnDefinition) ([]string, error) {
+	prefix := "ALTER TABLE " + table + " ALTER COLUMN " + d.QuoteIdent(to.ColumnName)
+	var stmts []string
+
+	if normalizeType(columnTypeOf(from)) != normalizeType(columnTypeOf(to)) {
+		stmts = append(stmts, prefix+" TYPE "+columnTypeOf(to))
+	}
+
+	if from.IsNullable != to.IsNullable {
+		if to.IsNullable {
+			stmts = append(stmts, prefix+" DROP NOT NULL")
+		} else {
+			stmts = append(stmts, prefix+" SET NOT NULL")
+		}
+	}
+
+	if from.AutoIncrement != to.AutoIncrement {
+		if to.AutoIncrement {
+			stmts = append(stmts, prefix+" ADD GENERATED BY DEFAULT AS IDENTITY")
+		} else {
+			stmts = append(stmts, prefix+" DROP IDENTITY IF EXISTS")
+		}
+	}
+
+	switch {
+	case to.Default == nil && from.Default != nil && !from.AutoIncrement:
+		stmts = append(stmts, prefix+" DROP DEFAULT")
+	case to.Default != nil && (from.Default == nil || normalizeDefault(*from.Default) != normalizeDefault(*to.Default)):
+		stmts = append(stmts, prefix+" SET DEFAULT "+*to.Default)
+	}
+
+	if len(to.Comment) > 0 && to.Comment != from.Comment {
+		stmts = append(stmts, "COMMENT ON COLUMN "+table+"."+d.QuoteIdent(to.ColumnName)+" IS '"+strings.ReplaceAll(to.Comment, "'", "''")+"'")
+	}
+	return stmts, nil
+}
+
+func (d postgresDialect) createIndexSQL(table string, idx *IndexDefinition, ifNotExists bool) (string, error) {
+	if idx.Primary {
+		return "ALTER TABLE " + table + " ADD PRIMARY KEY " + indexColumns(d, idx, false), nil
+	}
+	return createIndexStatement(d, table, d.QuoteIdent(idx.Name), idx, false, ifNotExists), nil
+}
+
+func (d postgresDialect) dropIndexSQL(table string, idx *IndexDefinition) (string, error) {
+	if idx.Primary {
+		return "ALTER TABLE " + table + " DROP CONSTRAINT " + d.QuoteIdent(idx.Name), nil
+	}
+	return "DROP INDEX " + d.QuoteIdent(idx.Name), nil
+}
+
+// SQLite, whose ALTER TABLE can only add, drop and rename columns.
+
+func (d sqliteDialect) columnClause(c *ColumnDefinition) string {
+	return columnClause(d, c, "")
+}
+
+func (d sqliteDialect) addColumnSQL(table string, c *ColumnDefinition) string {
+	return "ALTER TABLE " + table + " ADD COLUMN " + d.columnClause(c)
+}
+
+func (d sqliteDialect) dropColumnSQL(table, column string) string {
+	return "ALTER TABLE " + table + " DROP COLUMN " + d.QuoteIdent(column)
+}
+
+func (d sqliteDialect) modifyColumnSQL(table string, from, to *ColumnDefinition) ([]string, error) {
+	return nil, fmt.Errorf("SQLite can not modify column %s of %s, the table must be rebuilt", to.ColumnName, table)
+}
+
+func (d sqliteDialect) createIndexSQL(table string, idx *IndexDefinition, ifNotExists bool) (string, error) {
+	if idx.Primary {
+		return "", fmt.Errorf("SQLite can not change the primary key of %s, the table must be rebuilt", table)
+	}
+	if len(idx.Include) > 0 {
+		return "", notSupported(d, "INCLUDE columns")
+	}
+
+	// The schema qualifies the index name, not the table.
+	name := d.QuoteIdent(idx.Name)
+	if i := strings.LastIndex(table, "."); i > 0 {
+		name = table[:i+1] + name
+		table = table[i+1:]
+	}
+	return createIndexStatement(d, table, name, idx, false, ifNotExists), nil
+}
+
+func (d sqliteDialect) dropIndexSQL(table string, idx *IndexDefinition) (string, error) {
+	if idx.Primary {
+		return "", fmt.Errorf("SQLite can not change the primary key of %s, the table must be rebuilt", table)
+	}
+
+	name := d.QuoteIdent(idx.Name)
+	if i := strings.LastIndex(table, "."); i > 0 {
+		name = table[:i+1] + name
+	}
+	return "DROP INDEX " + name, nil
+}
+
+// SQL Server, where defaults are named constraints that can not be altered in
+// place.
+
+func (d sqlServerDialect) columnClause(c *ColumnDefinition) string {
+	return columnClause(d, c, "IDENTITY(1,1)")
+}
+
+func (d sqlServerDialect) addColumnSQL(table string, c *ColumnDefinition) string {
+	return "ALTER TABLE " + table + " ADD " + d.columnClause(c)
+}
+
+func (d sqlServerDialect) dropColumnSQL(table, column string) string {
+	return "ALTER TABLE " + table + " DROP COLUMN " + d.QuoteIdent(column)
+}
+
+func (d sqlServerDialect) modifyColumnSQL(table string, from, to *ColumnDefinition) ([]string, error) {
+	if from.AutoIncrement != to.AutoIncrement {
+		return nil, fmt.Errorf("SQL Server can not change the identity of column %s of %s, the table must be rebuilt", to.ColumnName, table)
+	}
+
+	if (from.Default == nil) != (to.Default == nil) || (to.Default != nil && normalizeDefault(*from.Default) != normalizeDefault(*to.Default)) {
+		return nil, fmt.Errorf("SQL Server can not change the default of column %s of %s in place", to.ColumnName, table)
+	}
+
+	s := "ALTER TABLE " + table + " ALTER COLUMN " + d.QuoteIdent(to.ColumnName) + " " + columnTypeOf(to)
+	if to.IsNullable {
+		s += " NULL"
+	} else {
+		s += " NOT NULL"
+	}
+	return []string{s}, nil
+}
+
+func (d sqlServerDialect) createIndexSQL(table string, idx *IndexDefinition, ifNotExists bool) (string, error) {
+	if idx.Primary {
+		return "ALTER TABLE " + table + " ADD PRIMARY KEY " + indexColumns(d, idx, false), nil
+	}
+
+	s := createIndexStatement(d, table, d.QuoteIdent(idx.Name), idx, false, false)
+	if ifNotExists {
+		s = "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = '" + strings.ReplaceAll(idx.Name, "'", "''") +
+			"' AND object_id = OBJECT_ID('" + strings.ReplaceAll(table, "'", "''") + "')) " + s
+	}
+	return s, nil
+}
+
+func (d sqlServerDialect) dropIndexSQL(table string, idx *IndexDefinition) (string, error) {
+	if idx.Primary {
+		return "ALTER TABLE " + table + " DROP CONSTRAINT " + d.QuoteIdent(idx.Name), nil
+	}
+	return "DROP INDEX " + d.QuoteIdent(idx.Name) + " ON " + table, nil
+}
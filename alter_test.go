@@ -0,0 +1,114 @@
+package picosql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanAlter(t *testing.T) {
+	str := func(s string) *string { return &s }
+	live := &TableStructure{
+		TableName: "accounts",
+		Columns: []*ColumnDefinition{
+			{ColumnName: "id", DataType: "int", ColumnType: "int(11)"},
+			{ColumnName: "status", DataType: "varchar", ColumnType: "varchar(20)", IsNullable: true, Default: str(mysqlDefault("active", false))},
+			{ColumnName: "legacy", DataType: "int", ColumnType: "int"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes: []*IndexDefinition{
+			{Name: "PRIMARY", Primary: true, Columns: []IndexColumn{{Name: "id"}}},
+			{Name: "ix_legacy", Columns: []IndexColumn{{Name: "legacy"}}},
+		},
+	}
+	desired := &TableStructure{
+		TableName: "accounts",
+		Columns: []*ColumnDefinition{
+			{ColumnName: "id", DataType: "int", ColumnType: "INT"},
+			{ColumnName: "status", DataType: "varchar", ColumnType: "varchar(40)", IsNullable: true, Default: str("'active'")},
+			{ColumnName: "email", DataType: "varchar", ColumnType: "varchar(100)"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes: []*IndexDefinition{
+			{Name: "ux_email", Unique: true, Columns: []IndexColumn{{Name: "email"}}},
+		},
+	}
+
+	stmts, err := PlanAlter(MySQLDialect, desired, live, AlterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ALTER TABLE `accounts` MODIFY COLUMN `status` varchar(40) NULL DEFAULT 'active'",
+		"ALTER TABLE `accounts` ADD COLUMN `email` varchar(100) NOT NULL",
+		"CREATE UNIQUE INDEX `ux_email` ON `accounts` (`email`)",
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(stmts, "\n"), strings.Join(want, "\n"))
+	}
+
+	stmts, err = PlanAlter(MySQLDialect, desired, live, AlterOptions{DropColumns: true, DropIndexes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append([]string{
+		"DROP INDEX `ix_legacy` ON `accounts`",
+		"ALTER TABLE `accounts` DROP COLUMN `legacy`",
+	}, want...)
+	if !reflect.DeepEqual(stmts, want) {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(stmts, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAlterTableSQLite(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT NOT NULL, legacy INTEGER)",
+		"CREATE INDEX ix_legacy ON accounts (legacy)",
+	)
+
+	desired, err := m.IntrospectTable("", "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired.Columns = append(desired.Columns[:2], &ColumnDefinition{ColumnName: "email", DataType: "text", ColumnType: "text", IsNullable: true})
+	desired.Indexes = []*IndexDefinition{{Name: "ux_accounts_email", Unique: true, Columns: []IndexColumn{{Name: "email"}}}}
+
+	stmts, err := m.AlterTable(desired, AlterOptions{DropColumns: true, DropIndexes: true})
+	if err != nil {
+		t.Fatalf("%v: %v", err, stmts)
+	}
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements: %v", len(stmts), stmts)
+	}
+
+	// The table matches now, so there is nothing left to do.
+	if stmts, err = m.AlterTable(desired, AlterOptions{DropColumns: true, DropIndexes: true}); err != nil || len(stmts) > 0 {
+		t.Fatalf("second run: %v, %v", stmts, err)
+	}
+
+	desired.Columns[1] = &ColumnDefinition{ColumnName: "name", DataType: "text", ColumnType: "text", IsNullable: true}
+	if _, err := m.AlterTable(desired, AlterOptions{DryRun: true, Out: &strings.Builder{}}); err == nil {
+		t.Fatal("SQLite modified a column")
+	}
+}
+
+func TestMySQLDefault(t *testing.T) {
+	for def, want := range map[string]string{
+		"active":            "'active'",
+		"it's":              "'it''s'",
+		"0":                 "0",
+		"-1.5":              "-1.5",
+		"'quoted'":          "'quoted'",
+		"NULL":              "NULL",
+		"b'1'":              "b'1'",
+		"CURRENT_TIMESTAMP": "CURRENT_TIMESTAMP",
+	} {
+		if got := mysqlDefault(def, false); got != want {
+			t.Errorf("mysqlDefault(%q) = %s, want %s", def, got, want)
+		}
+	}
+	if got := mysqlDefault("uuid()", true); got != "(uuid())" {
+		t.Errorf("generated default = %s", got)
+	}
+}
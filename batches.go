@@ -0,0 +1,99 @@
+package picosql
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrStopBatches can be returned from a SelectBatches callback to stop reading
+// further rows without SelectBatches reporting an error.
+var ErrStopBatches = errors.New("Stop batches")
+
+type BatchProgress struct {
+	Batch int   // 1-based number of the current batch
+	Rows  int   // rows in the current batch
+	Total int64 // rows processed so far, including the current batch
+}
+
+// SelectBatches streams query into targets, a pointer to a slice, batchSize
+// rows at a time and calls fn after each batch is filled. The slice and, for
+// slices of pointers, the structs it points to are reused between batches so
+// fn must copy anything it wants to keep.
+func (m *Sql) SelectBatches(targets interface{}, batchSize int, fn func(BatchProgress) error, query string, args ...interface{}) error {
+	if batchSize <= 0 {
+		return errors.New("Batch size must be greater than zero")
+	}
+
+	v := reflect.ValueOf(targets)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("Target must be a pointer to a slice")
+	}
+
+	sliceValue := v.Elem()
+	elementType := sliceValue.Type().Elem()
+	isPointer := elementType.Kind() == reflect.Ptr
+
+	if sliceValue.Cap() < batchSize {
+		sliceValue.Set(reflect.MakeSlice(sliceValue.Type(), 0, batchSize))
+	}
+	sliceValue.SetLen(0)
+
+	it, err := m.Iterate(query, args...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	progress := BatchProgress{}
+	flush := func() error {
+		progress.Batch++
+		progress.Rows = sliceValue.Len()
+		progress.Total += int64(progress.Rows)
+
+		err := fn(progress)
+		sliceValue.SetLen(0)
+		return err
+	}
+
+	for it.Next() {
+		n := sliceValue.Len()
+		sliceValue.SetLen(n + 1)
+
+		item := sliceValue.Index(n)
+		if isPointer {
+			if item.IsNil() {
+				item.Set(reflect.New(elementType.Elem()))
+			} else {
+				item.Elem().Set(reflect.Zero(elementType.Elem()))
+			}
+		} else {
+			item.Set(reflect.Zero(elementType))
+		}
+
+		if err := it.Scan(item.Addr().Interface()); err != nil {
+			return err
+		}
+
+		if n+1 < batchSize {
+			continue
+		}
+
+		if err := flush(); err != nil {
+			if err == ErrStopBatches {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	if sliceValue.Len() > 0 {
+		if err := flush(); err != nil && err != ErrStopBatches {
+			return err
+		}
+	}
+	return nil
+}
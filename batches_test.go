@@ -0,0 +1,79 @@
+package picosql
+
+import (
+	"errors"
+	"testing"
+)
+
+func newNumbers(t *testing.T, n int) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE numbers (n INTEGER, label TEXT)")
+	for i := 1; i <= n; i++ {
+		if _, err := m.Exec("INSERT INTO numbers VALUES (?, ?)", i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustExec(t, m, "UPDATE numbers SET label = 'odd' WHERE n % 2 = 1")
+	return m
+}
+
+type testNumber struct {
+	N     int     `db:"n"`
+	Label *string `db:"label"`
+}
+
+func TestSelectBatches(t *testing.T) {
+	m := newNumbers(t, 7)
+
+	var batch []*testNumber
+	var sizes []int
+	sum, labels := 0, 0
+	err := m.SelectBatches(&batch, 3, func(p BatchProgress) error {
+		sizes = append(sizes, p.Rows)
+		for _, r := range batch {
+			sum += r.N
+			if r.Label != nil {
+				labels++
+			}
+		}
+		return nil
+	}, "SELECT * FROM numbers ORDER BY n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[2] != 1 || sum != 28 {
+		t.Fatalf("sizes = %v, sum = %d", sizes, sum)
+	}
+	// Reused structs must not keep the label of an earlier row.
+	if labels != 4 {
+		t.Fatalf("%d labels, want 4", labels)
+	}
+}
+
+func TestSelectBatchesStop(t *testing.T) {
+	m := newNumbers(t, 10)
+
+	var batch []testNumber
+	calls := 0
+	err := m.SelectBatches(&batch, 2, func(p BatchProgress) error {
+		calls++
+		if p.Total >= 4 {
+			return ErrStopBatches
+		}
+		return nil
+	}, "SELECT * FROM numbers ORDER BY n")
+	if err != nil || calls != 2 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+
+	failure := errors.New("failure")
+	err = m.SelectBatches(&batch, 2, func(BatchProgress) error { return failure }, "SELECT * FROM numbers")
+	if err != failure {
+		t.Fatalf("err = %v", err)
+	}
+
+	if err := m.SelectBatches(batch, 2, func(BatchProgress) error { return nil }, "SELECT * FROM numbers"); err == nil {
+		t.Fatal("a non-pointer target was accepted")
+	}
+}
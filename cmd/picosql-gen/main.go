@@ -0,0 +1,67 @@
+// Command picosql-gen writes Go structs for the tables of a database.
+//
+//	picosql-gen -driver mysql -dsn 'user:pass@tcp(localhost:3306)/shop' -db shop -pkg models -out models/tables.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sfi2k7/picosql"
+)
+
+func main() {
+	driver := flag.String("driver", "mysql", "database/sql driver: mysql, postgres or sqlite3")
+	dsn := flag.String("dsn", "", "connection string")
+	db := flag.String("db", "", "database (schema) to read")
+	tables := flag.String("tables", "", "comma separated tables, all when empty")
+	pkg := flag.String("pkg", "models", "package of the generated file")
+	out := flag.String("out", "", "output file, stdout when empty")
+	json := flag.Bool("json", false, "add json tags")
+	null := flag.String("null", picosql.NullPointer, "nullable columns as pointer or sql (sql.Null* types)")
+	flag.Parse()
+
+	if len(*dsn) == 0 {
+		fmt.Fprintln(os.Stderr, "picosql-gen: -dsn is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ps, err := picosql.New(*driver, *dsn)
+	if err != nil {
+		fail(err)
+	}
+	defer ps.Close()
+
+	opts := picosql.GenerateOptions{Package: *pkg, JSONTags: *json, NullStyle: *null}
+	for _, t := range strings.Split(*tables, ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			opts.Tables = append(opts.Tables, t)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ps.GenerateStructs(&buf, *db, opts); err != nil {
+		fail(err)
+	}
+
+	if len(*out) == 0 {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "picosql-gen:", err)
+	os.Exit(1)
+}
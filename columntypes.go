@@ -0,0 +1,84 @@
+package picosql
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// SimplifyColumnTypes converts the column types reported by the driver into
+// the ColumnTypeSimplified form used by the DDL helpers.
+func SimplifyColumnTypes(types []*sql.ColumnType) []*ColumnTypeSimplified {
+	simplified := make([]*ColumnTypeSimplified, len(types))
+
+	for i, t := range types {
+		s := &ColumnTypeSimplified{
+			Index:  i,
+			Name:   t.Name(),
+			DBType: strings.ToUpper(t.DatabaseTypeName()),
+		}
+
+		if l, ok := t.Length(); ok && l < int64(^uint32(0)>>1) {
+			s.Length = int(l)
+		}
+
+		if nullable, ok := t.Nullable(); ok {
+			s.IsNullable = nullable
+		} else {
+			s.IsNullable = true
+		}
+
+		if p, sc, ok := t.DecimalSize(); ok {
+			s.Precison, s.Scale = int(p), int(sc)
+		}
+
+		if st := t.ScanType(); st != nil {
+			s.ScanType = st.String()
+		}
+
+		// Some drivers, like SQLite's, only report the declared type, e.g.
+		// VARCHAR(20) or DECIMAL(10,2).
+		if open := strings.Index(s.DBType, "("); open > 0 && strings.HasSuffix(s.DBType, ")") {
+			size := strings.Split(s.DBType[open+1:len(s.DBType)-1], ",")
+			s.DBType = strings.TrimSpace(s.DBType[:open])
+
+			first, _ := strconv.Atoi(strings.TrimSpace(size[0]))
+			if len(size) > 1 {
+				second, _ := strconv.Atoi(strings.TrimSpace(size[1]))
+				if s.Precison == 0 {
+					s.Precison, s.Scale = first, second
+				}
+			} else if s.Length == 0 {
+				s.Length = first
+			}
+		}
+
+		simplified[i] = s
+	}
+	return simplified
+}
+
+// DescribeQuery returns the columns of query without fetching any of its rows.
+func (m *Sql) DescribeQuery(query string, args ...interface{}) (*ColumnInfo, error) {
+	res, err := m.Query("SELECT * FROM ("+query+") AS picosql_describe WHERE 1 = 0", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	columns, err := res.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	types, err := res.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ColumnInfo{
+		Columns:     columns,
+		ColumnTypes: types,
+		Simplified:  SimplifyColumnTypes(types),
+	}, nil
+}
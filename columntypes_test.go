@@ -0,0 +1,30 @@
+package picosql
+
+import "testing"
+
+func TestDescribeQuery(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE items (id INTEGER NOT NULL, name VARCHAR(20), price DECIMAL(10,2))",
+		"INSERT INTO items VALUES (1, 'apple', 1.5)",
+	)
+
+	info, err := m.DescribeQuery("SELECT id, name, price FROM items WHERE id > ?", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Columns) != 3 || len(info.ColumnTypes) != 3 || len(info.Simplified) != 3 {
+		t.Fatalf("info = %+v", info)
+	}
+
+	id, name, price := info.Simplified[0], info.Simplified[1], info.Simplified[2]
+	if id.Name != "id" || id.DBType != "INTEGER" || id.Index != 0 {
+		t.Errorf("id = %+v", id)
+	}
+	if name.DBType != "VARCHAR" || name.Length != 20 || name.Index != 1 {
+		t.Errorf("name = %+v", name)
+	}
+	if price.DBType != "DECIMAL" || price.Precison != 10 || price.Scale != 2 {
+		t.Errorf("price = %+v", price)
+	}
+}
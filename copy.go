@@ -0,0 +1,246 @@
+package picosql
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type CopyOptions struct {
+	// BatchSize is the number of rows per INSERT statement, 500 when zero and
+	// fewer when the placeholders of the engine run out.
+	BatchSize int
+	// KeyColumn orders the copy and becomes the primary key of a created
+	// destination table. It is required for Resume.
+	KeyColumn string
+	// Resume skips source rows whose KeyColumn is not greater than the
+	// largest one already in the destination.
+	Resume bool
+	// RowsPerSecond throttles the copy when greater than zero.
+	RowsPerSecond int
+	// Progress is called after every batch; returning an error stops the copy.
+	Progress func(CopyProgress) error
+}
+
+type CopyProgress struct {
+	Batch   int
+	Rows    int64
+	LastKey interface{}
+	Elapsed time.Duration
+}
+
+type CopyResult struct {
+	Created bool
+	Rows    int64
+	Batches int
+	LastKey interface{}
+}
+
+// CopyTable streams the rows of srcQuery into dstTable, creating the table from
+// the source column types when it does not exist yet.
+func CopyTable(src *Sql, srcQuery string, dst *Sql, dstTable string, opts CopyOptions) (*CopyResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	if opts.Resume && len(opts.KeyColumn) == 0 {
+		return nil, errors.New("Resume requires a key column")
+	}
+
+	if _, err := dst.quoteName(dstTable); err != nil {
+		return nil, err
+	}
+	if err := dst.validateIdentifiers(opts.KeyColumn); err != nil {
+		return nil, err
+	}
+
+	info, err := src.DescribeQuery(srcQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	keyIndex := -1
+	for i, c := range info.Columns {
+		if c == opts.KeyColumn {
+			keyIndex = i
+		}
+	}
+
+	if len(opts.KeyColumn) > 0 && keyIndex < 0 {
+		return nil, errors.New("Key column is not part of the source query: " + opts.KeyColumn)
+	}
+
+	opts.BatchSize = dst.batchRows(opts.BatchSize, len(info.Columns))
+
+	result := &CopyResult{}
+	if !dst.tableExists(dstTable) {
+		columns, types := copyColumnTypes(info, opts.KeyColumn)
+		if len(opts.KeyColumn) > 0 {
+			err = dst.CreateTable(dstTable, columns, types, opts.KeyColumn)
+		} else {
+			err = dst.CreateTableNoHashOrKey(dstTable, columns, types)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Created = true
+	}
+
+	q := "SELECT * FROM (" + srcQuery + ") AS picosql_copy"
+	var args []interface{}
+
+	if opts.Resume && !result.Created {
+		var last interface{}
+		table, _ := dst.quoteName(dstTable)
+		if err := dst.QueryRow("SELECT MAX(" + dst.Dialect().QuoteIdent(opts.KeyColumn) + ") FROM " + table).Scan(&last); err != nil {
+			return nil, err
+		}
+		if b, ok := last.([]byte); ok {
+			last = string(b)
+		}
+		if last != nil {
+			q += " WHERE " + src.Dialect().QuoteIdent(opts.KeyColumn) + " > " + src.Dialect().Placeholder(1)
+			args = append(args, last)
+			result.LastKey = last
+		}
+	}
+
+	if len(opts.KeyColumn) > 0 {
+		q += " ORDER BY " + src.Dialect().QuoteIdent(opts.KeyColumn)
+	}
+
+	it, err := src.Iterate(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	started := time.Now()
+	rows := make([][]interface{}, 0, opts.BatchSize)
+
+	flush := func() error {
+		if err := dst.insertBatch(dstTable, info.Columns, rows); err != nil {
+			return err
+		}
+
+		result.Batches++
+		result.Rows += int64(len(rows))
+		if keyIndex >= 0 {
+			result.LastKey = rows[len(rows)-1][keyIndex]
+			if b, ok := result.LastKey.([]byte); ok {
+				result.LastKey = string(b)
+			}
+		}
+		rows = rows[:0]
+
+		if opts.RowsPerSecond > 0 {
+			expected := time.Duration(float64(result.Rows) / float64(opts.RowsPerSecond) * float64(time.Second))
+			if wait := expected - time.Since(started); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		if opts.Progress == nil {
+			return nil
+		}
+		return opts.Progress(CopyProgress{
+			Batch:   result.Batches,
+			Rows:    result.Rows,
+			LastKey: result.LastKey,
+			Elapsed: time.Since(started),
+		})
+	}
+
+	for it.Next() {
+		rows = append(rows, it.Values())
+		if len(rows) < opts.BatchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return result, err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return result, err
+	}
+
+	if len(rows) > 0 {
+		if err := flush(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// copyColumnTypes returns the columns and types to create the destination of
+// a copy with. CreateTable adds its own current_hash column, so the one of a
+// source made by CreateTable is left out when there is a key; its values are
+// still copied.
+func copyColumnTypes(info *ColumnInfo, keyColumn string) ([]string, []*ColumnTypeSimplified) {
+	var columns []string
+	var types []*ColumnTypeSimplified
+
+	for i, c := range info.Columns {
+		if len(keyColumn) > 0 && c == "current_hash" {
+			continue
+		}
+
+		t := *info.Simplified[i]
+		if len(t.DBType) == 0 {
+			// Expressions have no declared type on some engines.
+			t.DBType = scanTypeDBType(t.ScanType)
+		}
+
+		// Without a reported length, as with MySQL, a VARCHAR would get the
+		// default length and truncate longer values.
+		if t.Length <= 0 {
+			if tm := typeMappingsByName[strings.ToUpper(t.DBType)]; tm != nil && (tm == typeMappingsByName["VARCHAR"] || tm == typeMappingsByName["CHAR"]) {
+				if c == keyColumn {
+					t.Length = 255
+				} else {
+					t.DBType = "LONGTEXT"
+				}
+			}
+		}
+
+		columns = append(columns, c)
+		types = append(types, &t)
+	}
+	return columns, types
+}
+
+// scanTypeDBType picks a column type for a Go scan type, TEXT when there is
+// none that fits.
+func scanTypeDBType(scanType string) string {
+	switch strings.TrimPrefix(scanType, "sql.Null") {
+	case "int", "int8", "int16", "int32", "int64", "Int16", "Int32", "Int64", "Byte",
+		"uint", "uint8", "uint16", "uint32":
+		return "BIGINT"
+	case "uint64":
+		return "UNSIGNED BIGINT"
+	case "float32", "float64", "Float64":
+		return "DOUBLE"
+	case "bool", "Bool":
+		return "BOOLEAN"
+	case "time.Time", "Time":
+		return "DATETIME"
+	case "[]uint8", "sql.RawBytes":
+		return "LONGBLOB"
+	}
+	return "TEXT"
+}
+
+func (m *Sql) tableExists(table string) bool {
+	quoted, err := m.quoteName(table)
+	if err != nil {
+		return false
+	}
+
+	res, err := m.Query("SELECT * FROM " + quoted + " WHERE 1 = 0")
+	if err != nil {
+		return false
+	}
+	res.Close()
+	return true
+}
@@ -0,0 +1,99 @@
+package picosql
+
+import (
+	"errors"
+	"testing"
+)
+
+func newCopySource(t *testing.T) *Sql {
+	src := newTestDB(t)
+	types := []*ColumnTypeSimplified{{Name: "id", DBType: "INT"}, {Name: "name", DBType: "VARCHAR", Length: 20, IsNullable: true}}
+	if err := src.CreateTable("items", []string{"id", "name"}, types, "id"); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, src, "INSERT INTO items (id, name, current_hash) VALUES (1, 'apple', 'h1'), (2, NULL, 'h2'), (3, 'plum', 'h3')")
+	return src
+}
+
+func TestCopyTable(t *testing.T) {
+	src, dst := newCopySource(t), newTestDB(t)
+	query := "SELECT *, id * 2 AS n2 FROM items"
+
+	var batches []int64
+	opts := CopyOptions{KeyColumn: "id", BatchSize: 2, Resume: true, Progress: func(p CopyProgress) error {
+		batches = append(batches, p.Rows)
+		return nil
+	}}
+	res, err := CopyTable(src, query, dst, "copied", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Created || res.Rows != 3 || res.Batches != 2 || asInt(res.LastKey) != 3 {
+		t.Fatalf("result = %+v", res)
+	}
+	if len(batches) != 2 || batches[1] != 3 {
+		t.Errorf("progress = %v", batches)
+	}
+
+	rows, err := dst.Maps("SELECT id, name, n2, current_hash FROM copied ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || asInt(rows[2]["n2"]) != 6 || asString(rows[0]["current_hash"]) != "h1" || rows[1]["name"] != nil {
+		t.Fatalf("rows = %v", rows)
+	}
+
+	mustExec(t, src, "INSERT INTO items (id, name) VALUES (4, 'fig')")
+	if res, err = CopyTable(src, query, dst, "copied", opts); err != nil {
+		t.Fatal(err)
+	}
+	if res.Created || res.Rows != 1 || asInt(res.LastKey) != 4 {
+		t.Fatalf("resumed result = %+v", res)
+	}
+}
+
+func TestCopyTableProgressStop(t *testing.T) {
+	src, dst := newCopySource(t), newTestDB(t)
+	stop := errors.New("stop")
+	opts := CopyOptions{BatchSize: 1, Progress: func(CopyProgress) error { return stop }}
+	res, err := CopyTable(src, "SELECT id, name FROM items", dst, "copied", opts)
+	if err != stop {
+		t.Fatalf("err = %v", err)
+	}
+	if res.Rows != 1 {
+		t.Errorf("copied %d rows before stopping", res.Rows)
+	}
+}
+
+func TestCopyColumnTypes(t *testing.T) {
+	info := &ColumnInfo{
+		Columns: []string{"code", "note", "n", "current_hash"},
+		Simplified: []*ColumnTypeSimplified{
+			{Name: "code", DBType: "VARCHAR"},
+			{Name: "note", DBType: "VARCHAR"},
+			{Name: "n", ScanType: "int64"},
+			{Name: "current_hash", DBType: "VARCHAR", Length: 25},
+		},
+	}
+
+	columns, types := copyColumnTypes(info, "code")
+	if len(columns) != 3 {
+		t.Fatalf("columns = %v", columns)
+	}
+	if types[0].DBType != "VARCHAR" || types[0].Length != 255 {
+		t.Errorf("key = %+v", types[0])
+	}
+	if types[1].DBType != "LONGTEXT" {
+		t.Errorf("note = %+v", types[1])
+	}
+	if types[2].DBType != "BIGINT" {
+		t.Errorf("n = %+v", types[2])
+	}
+	if info.Simplified[1].DBType != "VARCHAR" {
+		t.Error("the source types were changed")
+	}
+
+	if columns, _ := copyColumnTypes(info, ""); len(columns) != 4 {
+		t.Errorf("columns without a key = %v", columns)
+	}
+}
@@ -0,0 +1,319 @@
+package picosql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateTableOptions struct {
+	// Table defaults to the value of a TableName() method, then to the
+	// struct name.
+	Table string
+	// DryRun prints the statements instead of running them.
+	DryRun bool
+	// Out receives the statements, os.Stdout in dry-run mode when nil.
+	Out io.Writer
+}
+
+// CreateTableFromStruct creates the table described by the fields and db tags
+// of v when it does not exist yet, with its indexes. Besides the column name
+// a tag takes these options:
+//
+//	pk              part of the primary key
+//	auto            auto increment
+//	size=100        length of strings and byte slices
+//	nullable        NULL even when the field is not a pointer
+//	notnull         NOT NULL even for a pointer or a byte slice
+//	precision=10    decimal column, with scale=2
+//	index, index=ix_name, unique, unique=ux_name
+//
+// Fields sharing an index name make a composite index, in field order.
+func (m *Sql) CreateTableFromStruct(v interface{}, opts CreateTableOptions) ([]string, error) {
+	ts, err := m.StructureOf(v, opts.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, err := CreateTableSQL(m.Dialect(), ts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := opts.Out
+	if out == nil && opts.DryRun {
+		out = os.Stdout
+	}
+
+	for _, s := range stmts {
+		if out != nil {
+			fmt.Fprintln(out, s+";")
+		}
+		if opts.DryRun {
+			continue
+		}
+		if _, err := m.Exec(s); err != nil {
+			return stmts, err
+		}
+	}
+	return stmts, nil
+}
+
+// CreateTableSQL returns the CREATE TABLE IF NOT EXISTS statement of ts and,
+// where indexes can not be declared inline, one statement per index.
+func CreateTableSQL(d Dialect, ts *TableStructure) ([]string, error) {
+	aw, err := alterWriterFor(d)
+	if err != nil {
+		return nil, err
+	}
+
+	table := quoteQualified(d, ts.DatabaseName, ts.TableName)
+	primary := ts.PrimaryKey
+
+	var defs []string
+	for _, c := range ts.Columns {
+		def := aw.columnClause(c)
+
+		// SQLite only auto increments an INTEGER PRIMARY KEY declared inline.
+		if d.Name() == "sqlite" && c.AutoIncrement {
+			if len(primary) != 1 || primary[0] != c.ColumnName {
+				return nil, fmt.Errorf("SQLite can only auto increment a single column primary key, not %s", c.ColumnName)
+			}
+			def = d.QuoteIdent(c.ColumnName) + " INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT"
+			primary = nil
+		}
+		defs = append(defs, def)
+	}
+
+	if len(primary) > 0 {
+		pk := make([]string, len(primary))
+		for i, c := range primary {
+			pk[i] = d.QuoteIdent(c)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pk, ", ")+")")
+	}
+
+	// SQL Server has inline indexes too, but not with every option
+	// createIndexSQL supports.
+	inline := d.Name() == "mysql"
+	var indexes []string
+	for _, idx := range ts.Indexes {
+		if idx.Primary {
+			continue
+		}
+
+		if inline {
+			s := "INDEX " + d.QuoteIdent(idx.Name) + " " + indexColumns(d, idx, true)
+			if idx.Unique {
+				s = "UNIQUE " + s
+			}
+			defs = append(defs, s)
+			continue
+		}
+
+		s, err := aw.createIndexSQL(table, idx, true)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, s)
+	}
+
+	create := "CREATE TABLE IF NOT EXISTS " + table + " (" + strings.Join(defs, ", ") + ")" + d.TableOptions()
+	if d.Name() == "sqlserver" {
+		create = "IF OBJECT_ID('" + strings.ReplaceAll(table, "'", "''") + "', 'U') IS NULL CREATE TABLE " + table + " (" + strings.Join(defs, ", ") + ")"
+	}
+	return append([]string{create}, indexes...), nil
+}
+
+// StructureOf builds the desired TableStructure of a table from the fields
+// and db tags of a struct, with the options CreateTableFromStruct documents.
+// table defaults to the value of a TableName() method, then to the struct
+// name.
+func (m *Sql) StructureOf(v interface{}, table string) (*TableStructure, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("StructureOf requires a struct")
+	}
+
+	if len(table) == 0 {
+		if tn, ok := v.(interface{ TableName() string }); ok {
+			table = tn.TableName()
+		} else {
+			table = t.Name()
+		}
+	}
+
+	d := m.Dialect()
+	if err := ValidateIdentifier(d, table); err != nil {
+		return nil, err
+	}
+
+	ts := &TableStructure{TableName: table}
+	indexes := make(map[string]*IndexDefinition)
+	for x := 0; x < t.NumField(); x++ {
+		f := t.Field(x)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, opts := fieldTag(f)
+		if name == "-" {
+			continue
+		}
+		if err := ValidateIdentifier(d, name); err != nil {
+			return nil, fmt.Errorf("Field %s: %v", f.Name, err)
+		}
+
+		sqlType, nullable, err := goColumnType(d, f.Type, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Field %s: %v", f.Name, err)
+		}
+
+		_, pk := opts["pk"]
+		_, auto := opts["auto"]
+		if pk {
+			nullable = false
+			ts.PrimaryKey = append(ts.PrimaryKey, name)
+		}
+
+		ts.Columns = append(ts.Columns, &ColumnDefinition{
+			ColumnName:      name,
+			OrdinalPosition: strconv.Itoa(len(ts.Columns) + 1),
+			ColumnType:      sqlType,
+			DataType:        strings.Split(sqlType, "(")[0],
+			IsNullable:      nullable,
+			AutoIncrement:   auto,
+		})
+
+		for _, kind := range []string{"index", "unique"} {
+			idxName, ok := opts[kind]
+			if !ok {
+				continue
+			}
+
+			if len(idxName) == 0 {
+				idxName = map[string]string{"index": "ix_", "unique": "ux_"}[kind] + table + "_" + name
+			}
+			if err := ValidateIdentifier(d, idxName); err != nil {
+				return nil, fmt.Errorf("Field %s: %v", f.Name, err)
+			}
+
+			idx, ok := indexes[idxName]
+			if !ok {
+				idx = &IndexDefinition{Name: idxName, Unique: kind == "unique"}
+				indexes[idxName] = idx
+				ts.Indexes = append(ts.Indexes, idx)
+			}
+			idx.Columns = append(idx.Columns, IndexColumn{Name: name})
+		}
+	}
+	return ts, nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	nullableSQL = map[reflect.Type]reflect.Type{
+		reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+		reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+		reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+		reflect.TypeOf(sql.NullInt16{}):   reflect.TypeOf(int16(0)),
+		reflect.TypeOf(sql.NullByte{}):    reflect.TypeOf(byte(0)),
+		reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+		reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+		reflect.TypeOf(sql.NullTime{}):    timeType,
+	}
+)
+
+// goColumnType maps a Go field type and its tag options to a column type of
+// dialect d. Pointers, sql.Null types and byte slices are nullable unless
+// tagged notnull.
+func goColumnType(d Dialect, t reflect.Type, opts map[string]string) (string, bool, error) {
+	_, nullable := opts["nullable"]
+	_, notNull := opts["notnull"]
+	if t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+	if inner, ok := nullableSQL[t]; ok {
+		nullable = true
+		t = inner
+	}
+
+	size, err := tagInt(opts, "size")
+	if err != nil {
+		return "", false, err
+	}
+
+	precision, err := tagInt(opts, "precision")
+	if err != nil {
+		return "", false, err
+	}
+
+	scale, err := tagInt(opts, "scale")
+	if err != nil {
+		return "", false, err
+	}
+
+	ct := &ColumnTypeSimplified{Name: t.Name(), IsNullable: nullable, Length: size, Precison: precision, Scale: scale}
+	switch {
+	// Decimal types from third party packages are structs named Decimal.
+	case precision > 0 || (t.Name() == "Decimal" && t.Kind() == reflect.Struct):
+		ct.DBType = "DECIMAL"
+	case t == timeType:
+		ct.DBType = "DATETIME"
+	case t.Kind() == reflect.Bool:
+		ct.DBType = "BOOLEAN"
+	case t.Kind() == reflect.Int8 || t.Kind() == reflect.Int16 || t.Kind() == reflect.Uint8:
+		ct.DBType = "SMALLINT"
+	case t.Kind() == reflect.Int32 || t.Kind() == reflect.Uint16:
+		ct.DBType = "INT"
+	case t.Kind() == reflect.Uint32:
+		ct.DBType = "UNSIGNED INT"
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		ct.DBType = "BIGINT"
+	case t.Kind() == reflect.Uint || t.Kind() == reflect.Uint64:
+		ct.DBType = "UNSIGNED BIGINT"
+	case t.Kind() == reflect.Float32:
+		ct.DBType = "FLOAT"
+	case t.Kind() == reflect.Float64:
+		ct.DBType = "DOUBLE"
+	case t.Kind() == reflect.String:
+		ct.DBType = "VARCHAR"
+		if size == 0 {
+			ct.Length = 255
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		ct.DBType, ct.IsNullable = "BLOB", true
+		if size > 0 {
+			ct.DBType = "VARBINARY"
+		}
+	default:
+		return "", false, fmt.Errorf("Unsupported type %s", t)
+	}
+
+	s, err := sqlType(d, ct)
+	return s, ct.IsNullable && !notNull, err
+}
+
+func tagInt(opts map[string]string, key string) (int, error) {
+	v, ok := opts[key]
+	if !ok || len(v) == 0 {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid %s %q", key, v)
+	}
+	return n, nil
+}
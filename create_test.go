@@ -0,0 +1,144 @@
+package picosql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOrder struct {
+	ID       int64     `db:"id,pk,auto"`
+	Customer string    `db:"customer,size=80,index=ix_orders_customer"`
+	Number   string    `db:"number,unique"`
+	Total    float64   `db:"total,precision=10,scale=2"`
+	Note     *string   `db:"note"`
+	Created  time.Time `db:"created_at,index=ix_orders_customer"`
+	Skipped  string    `db:"-"`
+}
+
+func (testOrder) TableName() string { return "orders" }
+
+func TestCreateTableFromStruct(t *testing.T) {
+	m := newTestDB(t)
+	if _, err := m.CreateTableFromStruct(testOrder{}, CreateTableOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := m.IntrospectTable("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	nullable := make(map[string]bool)
+	for _, c := range ts.Columns {
+		names = append(names, c.ColumnName)
+		nullable[c.ColumnName] = c.IsNullable
+	}
+	if got := strings.Join(names, ","); got != "id,customer,number,total,note,created_at" {
+		t.Errorf("columns = %s", got)
+	}
+	if !nullable["note"] || nullable["customer"] {
+		t.Errorf("nullable = %v", nullable)
+	}
+	if len(ts.PrimaryKey) != 1 || ts.PrimaryKey[0] != "id" {
+		t.Errorf("PrimaryKey = %v", ts.PrimaryKey)
+	}
+
+	indexes := make(map[string]*IndexDefinition)
+	for _, idx := range ts.Indexes {
+		indexes[idx.Name] = idx
+	}
+	if idx := indexes["ix_orders_customer"]; idx == nil || idx.Unique || len(idx.Columns) != 2 || idx.Columns[1].Name != "created_at" {
+		t.Errorf("ix_orders_customer = %+v", idx)
+	}
+	if idx := indexes["ux_orders_number"]; idx == nil || !idx.Unique {
+		t.Errorf("indexes = %v", ts.Indexes)
+	}
+
+	// The table exists now, so running again changes nothing.
+	if _, err := m.CreateTableFromStruct(&testOrder{}, CreateTableOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateTableFromStructDryRun(t *testing.T) {
+	m := newTestDB(t)
+	var buf bytes.Buffer
+	stmts, err := m.CreateTableFromStruct(testOrder{}, CreateTableOptions{Table: "drafts", DryRun: true, Out: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) == 0 || !strings.Contains(buf.String(), `CREATE TABLE IF NOT EXISTS "drafts"`) {
+		t.Fatalf("got %s", buf.String())
+	}
+
+	exists, err := m.Count("SELECT COUNT(*) FROM sqlite_master WHERE name = 'drafts'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists != 0 {
+		t.Fatal("a dry run created the table")
+	}
+}
+
+func TestCreateTableFromStructUnsupported(t *testing.T) {
+	type bad struct {
+		Tags map[string]string `db:"tags"`
+	}
+	if _, err := newTestDB(t).CreateTableFromStruct(bad{}, CreateTableOptions{DryRun: true, Out: &bytes.Buffer{}}); err == nil {
+		t.Fatal("a map field was mapped to a column")
+	}
+}
+
+func TestCreateTableSQLServerIndexes(t *testing.T) {
+	m := newTestDB(t)
+	ts, err := m.StructureOf(testOrder{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := CreateTableSQL(SQLServerDialect, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 || strings.Contains(stmts[0], "INDEX") {
+		t.Fatalf("statements = %q", stmts)
+	}
+	if !strings.Contains(stmts[2], "CREATE UNIQUE INDEX [ux_orders_number] ON [orders]") {
+		t.Errorf("unique index = %s", stmts[2])
+	}
+}
+
+func TestStructureOfTags(t *testing.T) {
+	m := newTestDB(t)
+
+	type blob struct {
+		Data  []byte `db:"data,notnull"`
+		Extra []byte `db:"extra"`
+		Ref   *int64 `db:"ref,notnull"`
+	}
+	ts, err := m.StructureOf(blob{}, "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Columns[0].IsNullable || !ts.Columns[1].IsNullable || ts.Columns[2].IsNullable {
+		t.Errorf("nullable = %v, %v, %v", ts.Columns[0].IsNullable, ts.Columns[1].IsNullable, ts.Columns[2].IsNullable)
+	}
+
+	type badColumn struct {
+		ID int64 `db:"id) ; DROP TABLE x; --"`
+	}
+	type badIndex struct {
+		ID int64 `db:"id,index=ix;x"`
+	}
+	for _, v := range []interface{}{badColumn{}, badIndex{}} {
+		if _, err := m.StructureOf(v, "t"); err == nil {
+			t.Errorf("%T: an invalid identifier was accepted", v)
+		}
+	}
+	if _, err := m.StructureOf(blob{}, "a;b"); err == nil {
+		t.Error("an invalid table name was accepted")
+	}
+}
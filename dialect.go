@@ -0,0 +1,394 @@
+package picosql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect holds everything that differs between the engines picosql talks to:
+// identifier quoting, placeholders, paging, type mapping, the queries used by
+// the schema and admin helpers, and DDL.
+//
+// Queries returned by a Dialect use the dialect's own placeholders and are
+// returned together with their arguments. An empty query means the engine
+// does not support the operation.
+type Dialect interface {
+	Name() string
+
+	// QuoteIdent quotes a single identifier such as a table or column name.
+	QuoteIdent(name string) string
+	// QuoteLiteral quotes a string value for statements that can not take
+	// bind parameters, such as CREATE USER.
+	QuoteLiteral(value string) string
+	// Placeholder returns the bind variable for the n-th (1-based) argument.
+	Placeholder(n int) string
+	// LimitOffset restricts query to a window of rows.
+	LimitOffset(query string, limit, offset int) string
+	// SupportsRowValues reports whether (a, b) > (?, ?) comparisons work.
+	SupportsRowValues() bool
+
+	// ColumnDefinition returns the column clause of CREATE TABLE for column
+	// name of type t, or an error when the type can not be mapped.
+	ColumnDefinition(name string, t *ColumnTypeSimplified) (string, error)
+	// TableOptions is appended after the closing parenthesis of CREATE TABLE.
+	TableOptions() string
+	CreateDatabaseSQL(db string) string
+	CreateUniqueIndexSQL(db, table, name string, columns []string) string
+
+	ListTablesQuery(db string) (string, []interface{})
+	ListDatabasesQuery() (string, []interface{})
+	UserExistsQuery(user string) (string, []interface{})
+	// TableInfoQuery returns a single row whose columns are named like the
+	// db tags of TableInfo.
+	TableInfoQuery(table string) (string, []interface{})
+	// ColumnsQuery returns column_name, ordinal_position, data_type and
+	// column_type for every column of table.
+	ColumnsQuery(db, table string) (string, []interface{})
+}
+
+// DialectFor returns the dialect matching a database/sql driver name. Unknown
+// drivers get the MySQL dialect, which is what picosql always assumed.
+func DialectFor(driver string) Dialect {
+	switch strings.ToLower(driver) {
+	case "postgres", "pgx", "pgx/v5", "cloudsqlpostgres":
+		return PostgresDialect
+	case "sqlite", "sqlite3":
+		return SQLiteDialect
+	case "sqlserver", "mssql", "azuresql":
+		return SQLServerDialect
+	}
+	return MySQLDialect
+}
+
+var (
+	MySQLDialect     Dialect = mysqlDialect{}
+	PostgresDialect  Dialect = postgresDialect{}
+	SQLiteDialect    Dialect = sqliteDialect{}
+	SQLServerDialect Dialect = sqlServerDialect{}
+)
+
+func (m *Sql) Dialect() Dialect {
+	if m.dialect == nil {
+		return DialectFor(m.driver)
+	}
+	return m.dialect
+}
+
+// SetDialect overrides the dialect chosen from the driver name, e.g. for
+// drivers registered under custom names.
+func (m *Sql) SetDialect(d Dialect) {
+	m.dialect = d
+}
+
+// quoteQualified quotes and joins the non-empty parts of a dotted name.
+func quoteQualified(d Dialect, parts ...string) string {
+	var quoted []string
+	for _, p := range parts {
+		if len(p) > 0 {
+			quoted = append(quoted, d.QuoteIdent(p))
+		}
+	}
+	return strings.Join(quoted, ".")
+}
+
+func limitOffsetClause(query string, limit, offset int) string {
+	query += " LIMIT " + strconv.Itoa(limit)
+	if offset > 0 {
+		query += " OFFSET " + strconv.Itoa(offset)
+	}
+	return query
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QuoteLiteral escapes backslashes too, which MySQL treats as escape
+// characters by default.
+func (mysqlDialect) QuoteLiteral(value string) string {
+	return "'" + strings.NewReplacer(
+		`\`, `\\`,
+		"'", "''",
+		"\x00", `\0`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\x1a", `\Z`,
+	).Replace(value) + "'"
+}
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+
+func (mysqlDialect) LimitOffset(query string, limit, offset int) string {
+	return limitOffsetClause(query, limit, offset)
+}
+
+func (mysqlDialect) SupportsRowValues() bool { return true }
+
+func (d mysqlDialect) ColumnDefinition(c string, t *ColumnTypeSimplified) (string, error) {
+	return columnDefinition(d, c, t)
+}
+
+func (mysqlDialect) TableOptions() string { return " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4" }
+
+func (d mysqlDialect) CreateDatabaseSQL(db string) string {
+	return "CREATE DATABASE " + d.QuoteIdent(db) + "  DEFAULT CHARACTER SET latin1"
+}
+
+func (d mysqlDialect) CreateUniqueIndexSQL(db, table, name string, columns []string) string {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = d.QuoteIdent(c) + " ASC"
+	}
+	return "ALTER TABLE " + quoteQualified(d, db, table) + " ADD UNIQUE INDEX " + d.QuoteIdent(name) + " (" + strings.Join(keys, ",") + ");"
+}
+
+func (mysqlDialect) ListTablesQuery(db string) (string, []interface{}) {
+	if len(db) == 0 {
+		return "SHOW tables;", nil
+	}
+	// views included, as SHOW tables lists them
+	return "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", []interface{}{db}
+}
+
+func (mysqlDialect) ListDatabasesQuery() (string, []interface{}) {
+	return "show databases", nil
+}
+
+func (mysqlDialect) UserExistsQuery(user string) (string, []interface{}) {
+	return "SELECT User from mysql.user WHERE User = ? LIMIT 1", []interface{}{user}
+}
+
+func (mysqlDialect) TableInfoQuery(table string) (string, []interface{}) {
+	return `SELECT TABLE_NAME AS Name, ENGINE AS Engine, VERSION AS Version, ROW_FORMAT AS Row_format,
+		TABLE_ROWS AS ` + "`Rows`" + `, AVG_ROW_LENGTH AS Average_row_length, DATA_LENGTH AS Data_length,
+		MAX_DATA_LENGTH AS Max_data_length, INDEX_LENGTH AS Index_length, DATA_FREE AS Data_free,
+		AUTO_INCREMENT AS Auto_increment, CREATE_TIME AS Create_time, UPDATE_TIME AS Update_time,
+		TABLE_COLLATION AS Collation, CHECKSUM AS Checksum, CREATE_OPTIONS AS Create_options, TABLE_COMMENT AS Comments
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, []interface{}{table}
+}
+
+func (mysqlDialect) ColumnsQuery(db, table string) (string, []interface{}) {
+	return `select column_name AS column_name, ordinal_position AS ordinal_position, data_type AS data_type, column_type AS column_type
+		from information_schema.COLUMNS where table_schema = COALESCE(NULLIF(?, ''), DATABASE()) and table_name = ? order by ordinal_position`, []interface{}{db, table}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral switches to an escape string when value holds backslashes, so
+// the result does not depend on standard_conforming_strings.
+func (postgresDialect) QuoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, "'", "''")
+	if strings.Contains(value, `\`) {
+		return "E'" + strings.ReplaceAll(value, `\`, `\\`) + "'"
+	}
+	return "'" + value + "'"
+}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) LimitOffset(query string, limit, offset int) string {
+	return limitOffsetClause(query, limit, offset)
+}
+
+func (postgresDialect) SupportsRowValues() bool { return true }
+
+func (d postgresDialect) ColumnDefinition(c string, t *ColumnTypeSimplified) (string, error) {
+	return columnDefinition(d, c, t)
+}
+
+func (postgresDialect) TableOptions() string { return "" }
+
+func (d postgresDialect) CreateDatabaseSQL(db string) string {
+	return "CREATE DATABASE " + d.QuoteIdent(db)
+}
+
+func (d postgresDialect) CreateUniqueIndexSQL(db, table, name string, columns []string) string {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = d.QuoteIdent(c) + " ASC"
+	}
+	return "CREATE UNIQUE INDEX " + d.QuoteIdent(name) + " ON " + quoteQualified(d, db, table) + " (" + strings.Join(keys, ", ") + ")"
+}
+
+func (postgresDialect) ListTablesQuery(db string) (string, []interface{}) {
+	return "SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname = COALESCE(NULLIF($1, ''), current_schema()) ORDER BY tablename", []interface{}{db}
+}
+
+func (postgresDialect) ListDatabasesQuery() (string, []interface{}) {
+	return "SELECT datname FROM pg_database WHERE NOT datistemplate", nil
+}
+
+func (postgresDialect) UserExistsQuery(user string) (string, []interface{}) {
+	return "SELECT rolname FROM pg_roles WHERE rolname = $1 LIMIT 1", []interface{}{user}
+}
+
+func (postgresDialect) TableInfoQuery(table string) (string, []interface{}) {
+	return `SELECT c.relname AS "Name", c.reltuples::bigint AS "Rows", pg_relation_size(c.oid) AS "Data_length", pg_indexes_size(c.oid) AS "Index_length"
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1 AND c.relkind = 'r' AND n.nspname = current_schema()`, []interface{}{table}
+}
+
+func (postgresDialect) ColumnsQuery(db, table string) (string, []interface{}) {
+	return `SELECT column_name, ordinal_position, data_type,
+		CASE
+			WHEN character_maximum_length IS NOT NULL THEN data_type || '(' || character_maximum_length || ')'
+			WHEN data_type = 'numeric' AND numeric_precision IS NOT NULL THEN data_type || '(' || numeric_precision || ',' || numeric_scale || ')'
+			ELSE data_type
+		END AS column_type
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`, []interface{}{db, table}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (sqliteDialect) QuoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+func (sqliteDialect) LimitOffset(query string, limit, offset int) string {
+	return limitOffsetClause(query, limit, offset)
+}
+
+func (sqliteDialect) SupportsRowValues() bool { return true }
+
+func (d sqliteDialect) ColumnDefinition(c string, t *ColumnTypeSimplified) (string, error) {
+	return columnDefinition(d, c, t)
+}
+
+func (sqliteDialect) TableOptions() string { return "" }
+
+func (sqliteDialect) CreateDatabaseSQL(db string) string { return "" }
+
+func (d sqliteDialect) CreateUniqueIndexSQL(db, table, name string, columns []string) string {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = d.QuoteIdent(c) + " ASC"
+	}
+	// SQLite qualifies the index, not the table, with the schema name.
+	return "CREATE UNIQUE INDEX " + quoteQualified(d, db, name) + " ON " + d.QuoteIdent(table) + " (" + strings.Join(keys, ", ") + ")"
+}
+
+func (sqliteDialect) ListTablesQuery(db string) (string, []interface{}) {
+	return "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name", nil
+}
+
+func (sqliteDialect) ListDatabasesQuery() (string, []interface{}) {
+	return "SELECT name FROM pragma_database_list", nil
+}
+
+func (sqliteDialect) UserExistsQuery(user string) (string, []interface{}) {
+	return "", nil
+}
+
+func (sqliteDialect) TableInfoQuery(table string) (string, []interface{}) {
+	return "SELECT name AS Name FROM sqlite_master WHERE type = 'table' AND name = ?", []interface{}{table}
+}
+
+func (sqliteDialect) ColumnsQuery(db, table string) (string, []interface{}) {
+	return `SELECT name AS column_name, cid + 1 AS ordinal_position,
+		lower(CASE WHEN instr(type, '(') > 0 THEN substr(type, 1, instr(type, '(') - 1) ELSE type END) AS data_type,
+		lower(type) AS column_type
+		FROM pragma_table_info(?)`, []interface{}{table}
+}
+
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Name() string { return "sqlserver" }
+
+func (sqlServerDialect) QuoteIdent(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (sqlServerDialect) QuoteLiteral(value string) string {
+	return "N'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (sqlServerDialect) Placeholder(n int) string { return "@p" + strconv.Itoa(n) }
+
+// LimitOffset uses OFFSET ... FETCH, which SQL Server only accepts after an
+// ORDER BY, so a neutral one is added when query has none.
+func (sqlServerDialect) LimitOffset(query string, limit, offset int) string {
+	if !strings.Contains(strings.ToLower(query), "order by") {
+		query += " ORDER BY (SELECT NULL)"
+	}
+	return query + " OFFSET " + strconv.Itoa(offset) + " ROWS FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+}
+
+func (sqlServerDialect) SupportsRowValues() bool { return false }
+
+func (d sqlServerDialect) ColumnDefinition(c string, t *ColumnTypeSimplified) (string, error) {
+	return columnDefinition(d, c, t)
+}
+
+func (sqlServerDialect) TableOptions() string { return "" }
+
+func (d sqlServerDialect) CreateDatabaseSQL(db string) string {
+	return "CREATE DATABASE " + d.QuoteIdent(db)
+}
+
+func (d sqlServerDialect) CreateUniqueIndexSQL(db, table, name string, columns []string) string {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = d.QuoteIdent(c) + " ASC"
+	}
+	return "CREATE UNIQUE INDEX " + d.QuoteIdent(name) + " ON " + quoteQualified(d, db, table) + " (" + strings.Join(keys, ", ") + ")"
+}
+
+func (d sqlServerDialect) ListTablesQuery(db string) (string, []interface{}) {
+	schema := "INFORMATION_SCHEMA.TABLES"
+	if len(db) > 0 {
+		schema = d.QuoteIdent(db) + "." + schema
+	}
+	return "SELECT TABLE_NAME FROM " + schema + " WHERE TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME", nil
+}
+
+func (sqlServerDialect) ListDatabasesQuery() (string, []interface{}) {
+	return "SELECT name FROM sys.databases", nil
+}
+
+func (sqlServerDialect) UserExistsQuery(user string) (string, []interface{}) {
+	return "SELECT TOP 1 name FROM sys.server_principals WHERE name = @p1", []interface{}{user}
+}
+
+func (sqlServerDialect) TableInfoQuery(table string) (string, []interface{}) {
+	return `SELECT t.name AS Name, SUM(p.rows) AS Rows
+		FROM sys.tables t JOIN sys.partitions p ON p.object_id = t.object_id AND p.index_id IN (0, 1)
+		WHERE t.name = @p1 GROUP BY t.name`, []interface{}{table}
+}
+
+func (sqlServerDialect) ColumnsQuery(db, table string) (string, []interface{}) {
+	return `SELECT COLUMN_NAME AS column_name, ORDINAL_POSITION AS ordinal_position, DATA_TYPE AS data_type,
+		CASE
+			WHEN CHARACTER_MAXIMUM_LENGTH = -1 THEN DATA_TYPE + '(max)'
+			WHEN CHARACTER_MAXIMUM_LENGTH IS NOT NULL THEN DATA_TYPE + '(' + CAST(CHARACTER_MAXIMUM_LENGTH AS VARCHAR) + ')'
+			WHEN DATA_TYPE IN ('decimal', 'numeric') THEN DATA_TYPE + '(' + CAST(NUMERIC_PRECISION AS VARCHAR) + ',' + CAST(NUMERIC_SCALE AS VARCHAR) + ')'
+			ELSE DATA_TYPE
+		END AS column_type
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE (@p1 = '' OR TABLE_CATALOG = @p1) AND TABLE_NAME = @p2
+		ORDER BY ORDINAL_POSITION`, []interface{}{db, table}
+}
+
+func notSupported(d Dialect, operation string) error {
+	return fmt.Errorf("%s is not supported by the %s dialect", operation, d.Name())
+}
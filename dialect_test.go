@@ -0,0 +1,42 @@
+package picosql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialectFor(t *testing.T) {
+	for driver, want := range map[string]Dialect{
+		"sqlite3":   SQLiteDialect,
+		"postgres":  PostgresDialect,
+		"sqlserver": SQLServerDialect,
+		"mysql":     MySQLDialect,
+		"unknown":   MySQLDialect,
+	} {
+		if got := DialectFor(driver); got != want {
+			t.Errorf("DialectFor(%q) = %s, want %s", driver, got.Name(), want.Name())
+		}
+	}
+}
+
+func TestListTablesQuery(t *testing.T) {
+	q, args := MySQLDialect.ListTablesQuery("shop")
+	if strings.Contains(q, "BASE TABLE") || len(args) != 1 || args[0] != "shop" {
+		t.Errorf("mysql: %s %v", q, args)
+	}
+
+	q, _ = SQLServerDialect.ListTablesQuery("sh]op")
+	if !strings.Contains(q, "FROM [sh]]op].INFORMATION_SCHEMA.TABLES") {
+		t.Errorf("sqlserver: %s", q)
+	}
+
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE b (id INTEGER)", "CREATE TABLE a (id INTEGER)")
+	tables, err := m.ListTables("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tables, ",") != "a,b" {
+		t.Errorf("tables = %v", tables)
+	}
+}
@@ -0,0 +1,396 @@
+package picosql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DiffRow struct {
+	Key    []interface{}          `json:"key"`
+	Values map[string]interface{} `json:"values"`
+}
+
+type ColumnDiff struct {
+	Column string      `json:"column"`
+	A      interface{} `json:"a"`
+	B      interface{} `json:"b"`
+}
+
+type RowDiff struct {
+	Key     []interface{} `json:"key"`
+	Columns []ColumnDiff  `json:"columns"`
+}
+
+type DiffResult struct {
+	KeyColumns []string   `json:"keyColumns"`
+	OnlyInA    []*DiffRow `json:"onlyInA"`
+	OnlyInB    []*DiffRow `json:"onlyInB"`
+	Changed    []*RowDiff `json:"changed"`
+	Same       int64      `json:"same"`
+}
+
+type diffSide struct {
+	name    string
+	it      *Iterator
+	keys    []int
+	numeric []bool
+	values  []interface{}
+	key     []interface{}
+	prevKey []interface{}
+	done    bool
+}
+
+// Diff compares the rows of queryA on a with the rows of queryB on b, matched
+// by keyColumns. Both sides are streamed ordered by key, so the key columns
+// must sort the same way in both engines; a side that comes back out of order
+// (e.g. because of a case-insensitive collation) fails the diff.
+func Diff(a *Sql, queryA string, b *Sql, queryB string, keyColumns []string) (*DiffResult, error) {
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("Diff requires at least one key column")
+	}
+
+	sa, err := openDiffSide("A", a, queryA, keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer sa.it.Close()
+
+	sb, err := openDiffSide("B", b, queryB, keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.it.Close()
+
+	var common [][2]int
+	for i, c := range sa.it.Columns() {
+		for j, d := range sb.it.Columns() {
+			if c == d {
+				common = append(common, [2]int{i, j})
+				break
+			}
+		}
+	}
+
+	result := &DiffResult{KeyColumns: keyColumns}
+	for _, s := range []*diffSide{sa, sb} {
+		if err := s.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	for !sa.done || !sb.done {
+		c := 0
+		switch {
+		case sa.done:
+			c = 1
+		case sb.done:
+			c = -1
+		default:
+			c = compareKeys(sa.key, sb.key)
+		}
+
+		switch {
+		case c < 0:
+			result.OnlyInA = append(result.OnlyInA, sa.row())
+			err = sa.next()
+		case c > 0:
+			result.OnlyInB = append(result.OnlyInB, sb.row())
+			err = sb.next()
+		default:
+			var columns []ColumnDiff
+			for _, p := range common {
+				va, vb := diffValue(sa.values[p[0]]), diffValue(sb.values[p[1]])
+				if !equalValues(va, vb) {
+					columns = append(columns, ColumnDiff{Column: sa.it.Columns()[p[0]], A: va, B: vb})
+				}
+			}
+
+			if len(columns) == 0 {
+				result.Same++
+			} else {
+				result.Changed = append(result.Changed, &RowDiff{Key: sa.row().Key, Columns: columns})
+			}
+
+			if err = sa.next(); err == nil {
+				err = sb.next()
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// diffCollations sorts a text key by its bytes on each engine.
+var diffCollations = map[string]string{
+	"mysql":     "CAST(%s AS BINARY)",
+	"postgres":  `%s COLLATE "C"`,
+	"sqlite":    "%s COLLATE BINARY",
+	"sqlserver": "%s COLLATE Latin1_General_BIN2",
+}
+
+func isTextColumn(t *sql.ColumnType) bool {
+	name := strings.ToUpper(t.DatabaseTypeName())
+	return strings.Contains(name, "CHAR") || strings.Contains(name, "TEXT") || strings.Contains(name, "CLOB")
+}
+
+func openDiffSide(name string, m *Sql, query string, keyColumns []string) (*diffSide, error) {
+	orderBy, err := m.quoteColumns(keyColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := m.DescribeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	described, err := columnIndexes(info.Columns, keyColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	// compareValues orders text by bytes, whatever the collation of the
+	// column, and sorts NULL first, as the engines other than Postgres do.
+	collate := diffCollations[m.Dialect().Name()]
+	for i, idx := range described {
+		if len(collate) > 0 && isTextColumn(info.ColumnTypes[idx]) {
+			orderBy[i] = fmt.Sprintf(collate, orderBy[i])
+		}
+		if m.Dialect().Name() == "postgres" {
+			orderBy[i] += " NULLS FIRST"
+		}
+	}
+
+	it, err := m.Iterate("SELECT * FROM (" + query + ") AS picosql_diff ORDER BY " + strings.Join(orderBy, ", "))
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := columnIndexes(it.Columns(), keyColumns)
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+
+	types, err := it.rows.ColumnTypes()
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+
+	numeric := make([]bool, len(keys))
+	for i, idx := range keys {
+		numeric[i] = isNumericColumn(types[idx])
+	}
+	return &diffSide{name: name, it: it, keys: keys, numeric: numeric}, nil
+}
+
+func (s *diffSide) next() error {
+	if !s.it.Next() {
+		s.done = true
+		return s.it.Err()
+	}
+
+	s.values = s.it.Values()
+	s.prevKey = s.key
+	s.key = make([]interface{}, len(s.keys))
+	for i, idx := range s.keys {
+		s.key[i] = diffValue(s.values[idx])
+
+		// Drivers using a text protocol return numbers as text, which must
+		// not be compared as strings.
+		if str, ok := s.key[i].(string); ok && s.numeric[i] {
+			if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+				s.key[i] = n
+			} else if f, err := strconv.ParseFloat(str, 64); err == nil {
+				s.key[i] = f
+			}
+		}
+	}
+
+	if s.prevKey != nil && compareKeys(s.prevKey, s.key) > 0 {
+		return fmt.Errorf("Rows of %s are not ordered by key as expected at %v", s.name, s.key)
+	}
+	return nil
+}
+
+func (s *diffSide) row() *DiffRow {
+	r := &DiffRow{Key: s.key, Values: make(map[string]interface{})}
+	for i, c := range s.it.Columns() {
+		r.Values[c] = diffValue(s.values[i])
+	}
+	return r
+}
+
+func diffValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func compareKeys(a, b []interface{}) int {
+	for i := range a {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	if ia, ok := a.(int64); ok {
+		if ib, ok := b.(int64); ok {
+			switch {
+			case ia < ib:
+				return -1
+			case ia > ib:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	if fa, ok := numericValue(a); ok {
+		if fb, ok := numericValue(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(hashText(a), hashText(b))
+}
+
+func equalValues(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if compareValues(a, b) == 0 {
+		return true
+	}
+
+	fa, ok := numericValue(a)
+	if !ok {
+		fa, ok = parseNumber(a)
+	}
+	fb, ok2 := numericValue(b)
+	if !ok2 {
+		fb, ok2 = parseNumber(b)
+	}
+	return ok && ok2 && fa == fb
+}
+
+func parseNumber(v interface{}) (float64, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f, err == nil
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch nv := v.(type) {
+	case int64:
+		return float64(nv), true
+	case int:
+		return float64(nv), true
+	case int32:
+		return float64(nv), true
+	case uint64:
+		return float64(nv), true
+	case float64:
+		return nv, true
+	case float32:
+		return float64(nv), true
+	}
+	return 0, false
+}
+
+// WriteJSON writes the diff as a single JSON document.
+func (d *DiffResult) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(d)
+}
+
+// WriteCSV writes one line per difference: the kind of change, the key
+// columns, and the differing column with its values on both sides. Rows that
+// exist on one side only are written with an empty column.
+func (d *DiffResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := append(append([]string{"change"}, d.KeyColumns...), "column", "a", "b")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	line := func(change string, key []interface{}, column string, a, b interface{}) error {
+		record := []string{change}
+		for _, k := range key {
+			record = append(record, diffText(k))
+		}
+		record = append(record, column, diffText(a), diffText(b))
+		return cw.Write(record)
+	}
+
+	for _, r := range d.OnlyInA {
+		if err := line("only_in_a", r.Key, "", nil, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range d.OnlyInB {
+		if err := line("only_in_b", r.Key, "", nil, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range d.Changed {
+		for _, c := range r.Columns {
+			if err := line("changed", r.Key, c.Column, c.A, c.B); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func diffText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return hashText(v)
+}
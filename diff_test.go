@@ -0,0 +1,75 @@
+package picosql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, b := newTestDB(t), newTestDB(t)
+	mustExec(t, a,
+		"CREATE TABLE items (id INTEGER, name TEXT, price REAL)",
+		"INSERT INTO items VALUES (NULL, 'none', 0), (1, 'apple', 1.5), (2, 'pear', 2), (3, 'plum', 0.5)",
+	)
+	mustExec(t, b,
+		"CREATE TABLE items (id INTEGER, name TEXT, price REAL)",
+		"INSERT INTO items VALUES (NULL, 'none', 0), (1, 'apple', 1.5), (2, 'pear', 2.5), (4, 'fig', 4)",
+	)
+
+	d, err := Diff(a, "SELECT * FROM items", b, "SELECT * FROM items", []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Same != 2 {
+		t.Errorf("Same = %d, want 2", d.Same)
+	}
+	if len(d.OnlyInA) != 1 || fmt.Sprint(d.OnlyInA[0].Key[0]) != "3" {
+		t.Errorf("OnlyInA = %v", d.OnlyInA)
+	}
+	if len(d.OnlyInB) != 1 || fmt.Sprint(d.OnlyInB[0].Key[0]) != "4" {
+		t.Errorf("OnlyInB = %v", d.OnlyInB)
+	}
+	if len(d.Changed) != 1 || len(d.Changed[0].Columns) != 1 || d.Changed[0].Columns[0].Column != "price" {
+		t.Fatalf("Changed = %v", d.Changed)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Fatalf("invalid JSON: %s", buf.String())
+	}
+}
+
+func TestDiffCollation(t *testing.T) {
+	a, b := newTestDB(t), newTestDB(t)
+	mustExec(t, a,
+		"CREATE TABLE items (name TEXT)",
+		"INSERT INTO items VALUES ('B'), ('a'), ('c')",
+	)
+	mustExec(t, b,
+		"CREATE TABLE items (name TEXT COLLATE NOCASE)",
+		"INSERT INTO items VALUES ('B'), ('a'), ('c')",
+	)
+
+	// The case-insensitive side would come back as a, B, c, unless the
+	// keys are ordered by their bytes.
+	d, err := Diff(a, "SELECT * FROM items", b, "SELECT * FROM items", []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Same != 3 {
+		t.Errorf("Same = %d, want 3", d.Same)
+	}
+}
+
+func TestDiffRequiresKey(t *testing.T) {
+	a := newTestDB(t)
+	if _, err := Diff(a, "SELECT 1", a, "SELECT 1", nil); err == nil {
+		t.Fatal("a diff without key columns ran")
+	}
+}
@@ -0,0 +1,246 @@
+package picosql
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+type ExportFormat int
+
+const (
+	FormatCSV ExportFormat = iota
+	FormatJSON
+	FormatNDJSON
+)
+
+type ExportOptions struct {
+	Format ExportFormat
+
+	// Headers replaces the column names of the result set, in order.
+	Headers []string
+	// NoHeader omits the header line of CSV output.
+	NoHeader bool
+	// Comma is the CSV field delimiter, ',' when zero.
+	Comma rune
+	// Null is written for NULL values in CSV output.
+	Null string
+	// OmitNull leaves NULL columns out of JSON objects instead of writing null.
+	OmitNull bool
+	// TimeFormat is used for time values, time.RFC3339Nano when empty.
+	TimeFormat string
+	// BytesAsBase64 writes binary columns base64 encoded instead of as strings.
+	BytesAsBase64 bool
+}
+
+func (m *Sql) ExportCSV(w io.Writer, query string, args ...interface{}) error {
+	return m.Export(w, ExportOptions{Format: FormatCSV}, query, args...)
+}
+
+func (m *Sql) ExportJSON(w io.Writer, query string, args ...interface{}) error {
+	return m.Export(w, ExportOptions{Format: FormatJSON}, query, args...)
+}
+
+func (m *Sql) ExportNDJSON(w io.Writer, query string, args ...interface{}) error {
+	return m.Export(w, ExportOptions{Format: FormatNDJSON}, query, args...)
+}
+
+// Export streams the rows of query to w row by row without holding the result
+// set in memory.
+func (m *Sql) Export(w io.Writer, opts ExportOptions, query string, args ...interface{}) error {
+	it, err := m.Iterate(query, args...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	types, err := it.rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	headers := it.Columns()
+	if len(opts.Headers) > 0 {
+		if len(opts.Headers) != len(headers) {
+			return fmt.Errorf("Expected %d headers, got %d", len(headers), len(opts.Headers))
+		}
+		headers = opts.Headers
+	}
+
+	if len(opts.TimeFormat) == 0 {
+		opts.TimeFormat = time.RFC3339Nano
+	}
+
+	e := &exporter{opts: opts, headers: headers, types: types}
+
+	switch opts.Format {
+	case FormatCSV:
+		err = e.csv(w, it)
+	case FormatJSON, FormatNDJSON:
+		err = e.json(w, it)
+	default:
+		err = errors.New("Unknown export format")
+	}
+
+	if err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+type exporter struct {
+	opts    ExportOptions
+	headers []string
+	types   []*sql.ColumnType
+}
+
+func (e *exporter) csv(w io.Writer, it *Iterator) error {
+	cw := csv.NewWriter(w)
+	if e.opts.Comma != 0 {
+		cw.Comma = e.opts.Comma
+	}
+
+	if !e.opts.NoHeader {
+		if err := cw.Write(e.headers); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(e.headers))
+	for it.Next() {
+		for i, v := range it.Values() {
+			record[i] = e.text(i, v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (e *exporter) json(w io.Writer, it *Iterator) error {
+	bw := bufio.NewWriter(w)
+	array := e.opts.Format == FormatJSON
+
+	keys := make([][]byte, len(e.headers))
+	for i, h := range e.headers {
+		keys[i], _ = json.Marshal(h)
+	}
+
+	if array {
+		bw.WriteString("[")
+	}
+
+	first := true
+	for it.Next() {
+		if array && !first {
+			bw.WriteString(",")
+		}
+		first = false
+
+		bw.WriteString("{")
+		written := false
+		for i, v := range it.Values() {
+			if v == nil && e.opts.OmitNull {
+				continue
+			}
+
+			value, err := e.jsonValue(i, v)
+			if err != nil {
+				return err
+			}
+
+			if written {
+				bw.WriteString(",")
+			}
+			written = true
+
+			bw.Write(keys[i])
+			bw.WriteString(":")
+			bw.Write(value)
+		}
+		bw.WriteString("}")
+
+		if !array {
+			bw.WriteString("\n")
+		}
+	}
+
+	if array {
+		bw.WriteString("]\n")
+	}
+	return bw.Flush()
+}
+
+func (e *exporter) text(i int, v interface{}) string {
+	switch nv := v.(type) {
+	case nil:
+		return e.opts.Null
+	case []byte:
+		if e.opts.BytesAsBase64 && isBinaryColumn(e.types[i]) {
+			return base64.StdEncoding.EncodeToString(nv)
+		}
+		return string(nv)
+	case time.Time:
+		return nv.Format(e.opts.TimeFormat)
+	case string:
+		return nv
+	}
+	return fmt.Sprint(v)
+}
+
+func (e *exporter) jsonValue(i int, v interface{}) ([]byte, error) {
+	switch nv := v.(type) {
+	case []byte:
+		if isNumericColumn(e.types[i]) && isJSONNumber(nv) {
+			return nv, nil
+		}
+		return json.Marshal(e.text(i, v))
+	case time.Time:
+		return json.Marshal(e.text(i, v))
+	case float64:
+		if math.IsNaN(nv) || math.IsInf(nv, 0) {
+			return json.Marshal(e.text(i, v))
+		}
+	case float32:
+		if math.IsNaN(float64(nv)) || math.IsInf(float64(nv), 0) {
+			return json.Marshal(e.text(i, v))
+		}
+	}
+	return json.Marshal(v)
+}
+
+// isJSONNumber tells whether b can be written as a JSON number as it is,
+// unlike NaN, Infinity, +5 or hexadecimal values.
+func isJSONNumber(b []byte) bool {
+	if len(b) == 0 || !json.Valid(b) {
+		return false
+	}
+	first, last := b[0], b[len(b)-1]
+	return (first == '-' || first >= '0' && first <= '9') && last >= '0' && last <= '9'
+}
+
+func isBinaryColumn(t *sql.ColumnType) bool {
+	name := strings.ToUpper(t.DatabaseTypeName())
+	return strings.Contains(name, "BLOB") || strings.Contains(name, "BINARY") || name == "BYTEA" || name == "IMAGE"
+}
+
+func isNumericColumn(t *sql.ColumnType) bool {
+	switch strings.ToUpper(t.DatabaseTypeName()) {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT",
+		"INT2", "INT4", "INT8", "DECIMAL", "NUMERIC", "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "REAL", "YEAR":
+		return true
+	}
+	return false
+}
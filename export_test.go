@@ -0,0 +1,72 @@
+package picosql
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newExportTable(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE items (id INTEGER, name TEXT, price REAL)",
+		"INSERT INTO items VALUES (1, 'apple', 1.5), (2, NULL, 2), (3, 'say \"hi\"', NULL)",
+	)
+	return m
+}
+
+func TestExportCSV(t *testing.T) {
+	m := newExportTable(t)
+	var buf bytes.Buffer
+	err := m.Export(&buf, ExportOptions{Format: FormatCSV, Null: `\N`}, "SELECT * FROM items ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,name,price\n1,apple,1.5\n2,\\N,2\n3,\"say \"\"hi\"\"\",\\N\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	m := newExportTable(t)
+	var buf bytes.Buffer
+	if err := m.ExportJSON(&buf, "SELECT * FROM items ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if len(rows) != 3 || rows[0]["price"] != 1.5 || rows[1]["name"] != nil || rows[2]["name"] != `say "hi"` {
+		t.Fatalf("rows = %v", rows)
+	}
+}
+
+func TestExportNDJSONOmitNull(t *testing.T) {
+	m := newExportTable(t)
+	var buf bytes.Buffer
+	err := m.Export(&buf, ExportOptions{Format: FormatNDJSON, OmitNull: true}, "SELECT * FROM items ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || strings.Contains(lines[1], "name") {
+		t.Fatalf("got %s", buf.String())
+	}
+}
+
+func TestIsJSONNumber(t *testing.T) {
+	for s, want := range map[string]bool{
+		"5": true, "-1.5e3": true, "0.25": true,
+		"NaN": false, "Inf": false, "+5": false, "0x1F": false, " 5": false, "1.": false, "": false,
+	} {
+		if got := isJSONNumber([]byte(s)); got != want {
+			t.Errorf("isJSONNumber(%q) = %v", s, got)
+		}
+	}
+}
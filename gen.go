@@ -0,0 +1,225 @@
+package picosql
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Null styles of GenerateOptions.
+const (
+	NullPointer = "pointer"
+	NullSQL     = "sql"
+)
+
+type GenerateOptions struct {
+	// Package of the generated file, models when empty.
+	Package string
+	// Tables to generate, every table of the database when empty.
+	Tables []string
+	// JSONTags adds json tags named like the columns.
+	JSONTags bool
+	// NullStyle is NullPointer (default) for *T fields or NullSQL for
+	// sql.NullString and friends.
+	NullStyle string
+	// Dialect is the Name of the dialect the tables come from, set by
+	// Sql.GenerateStructs. SQLite integers of every declared type are 64 bit.
+	Dialect string
+}
+
+// GenerateStructs writes a Go file with one struct per table of db, with db
+// tags and a TableName() method.
+func (m *Sql) GenerateStructs(w io.Writer, db string, opts GenerateOptions) error {
+	tables := opts.Tables
+	if len(tables) == 0 {
+		var err error
+		if tables, err = m.ListTables(db); err != nil {
+			return err
+		}
+	}
+
+	_, canIntrospect := m.Dialect().(schemaIntrospector)
+	structures := make([]*TableStructure, 0, len(tables))
+	for _, table := range tables {
+		var ts *TableStructure
+		var err error
+		if canIntrospect {
+			ts, err = m.IntrospectTable(db, table)
+		} else {
+			ts, err = m.GetCurrentStructure(db, table)
+		}
+		if err != nil {
+			return err
+		}
+		structures = append(structures, ts)
+	}
+
+	if len(opts.Dialect) == 0 {
+		opts.Dialect = m.Dialect().Name()
+	}
+	return GenerateStructs(w, structures, opts)
+}
+
+// GenerateStructs writes a Go file with one struct per table structure.
+func GenerateStructs(w io.Writer, tables []*TableStructure, opts GenerateOptions) error {
+	if len(opts.Package) == 0 {
+		opts.Package = "models"
+	}
+	if len(opts.NullStyle) == 0 {
+		opts.NullStyle = NullPointer
+	}
+	if opts.NullStyle != NullPointer && opts.NullStyle != NullSQL {
+		return fmt.Errorf("Unknown null style %s", opts.NullStyle)
+	}
+
+	imports := make(map[string]bool)
+	var body bytes.Buffer
+	for _, ts := range tables {
+		name := goIdentifier(ts.TableName)
+		primary := make(map[string]bool)
+		for _, c := range ts.PrimaryKey {
+			primary[c] = true
+		}
+
+		fmt.Fprintf(&body, "\ntype %s struct {\n", name)
+		used := make(map[string]bool)
+		for _, c := range ts.Columns {
+			field := goIdentifier(c.ColumnName)
+			for x := 2; used[field]; x++ {
+				field = fmt.Sprintf("%s%d", goIdentifier(c.ColumnName), x)
+			}
+			used[field] = true
+
+			goType, pkg := goFieldType(c, opts.NullStyle, opts.Dialect)
+			if len(pkg) > 0 {
+				imports[pkg] = true
+			}
+
+			tag := c.ColumnName
+			if primary[c.ColumnName] {
+				tag += ",pk"
+			}
+			if c.AutoIncrement {
+				tag += ",auto"
+			}
+
+			tags := fmt.Sprintf("db:%q", tag)
+			if opts.JSONTags {
+				tags += fmt.Sprintf(" json:%q", c.ColumnName)
+			}
+			fmt.Fprintf(&body, "\t%s %s `%s`\n", field, goType, tags)
+		}
+		fmt.Fprintf(&body, "}\n\nfunc (%s) TableName() string { return %q }\n", name, ts.TableName)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by picosql-gen. DO NOT EDIT.\n\npackage %s\n", opts.Package)
+	if len(imports) > 0 {
+		pkgs := make([]string, 0, len(imports))
+		for p := range imports {
+			pkgs = append(pkgs, p)
+		}
+		sort.Strings(pkgs)
+
+		src.WriteString("\nimport (\n")
+		for _, p := range pkgs {
+			fmt.Fprintf(&src, "\t%q\n", p)
+		}
+		src.WriteString(")\n")
+	}
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// goFieldType returns the Go type of a column and the package it needs.
+func goFieldType(c *ColumnDefinition, nullStyle, dialect string) (string, string) {
+	columnType := strings.ToLower(columnTypeOf(c))
+	dataType := strings.ToLower(c.DataType)
+	if len(dataType) == 0 {
+		dataType = strings.Split(columnType, "(")[0]
+	}
+	unsigned := strings.Contains(columnType, "unsigned") || strings.Contains(dataType, "unsigned")
+	dataType = strings.TrimSpace(strings.Replace(dataType, "unsigned", "", 1))
+
+	goType, pkg := "string", ""
+	switch {
+	case columnType == "tinyint(1)" || dataType == "bool" || dataType == "boolean" || dataType == "bit":
+		goType = "bool"
+	case dataType == "tinyint" || (!unsigned && (dataType == "smallint" || dataType == "int2")):
+		goType = "int16"
+	case unsigned && dataType == "bigint":
+		goType = "uint64"
+	case dataType == "bigint" || dataType == "int8" || (unsigned && dataType == "int") ||
+		(dialect == "sqlite" && strings.Contains(dataType, "int")):
+		goType = "int64"
+	case strings.Contains(dataType, "int") && !strings.Contains(dataType, "interval") && !strings.Contains(dataType, "point"):
+		goType = "int32"
+	case dataType == "decimal" || dataType == "numeric" || dataType == "money":
+		goType = "float64"
+	case strings.Contains(dataType, "float") || strings.Contains(dataType, "double") || dataType == "real":
+		goType = "float64"
+	case strings.Contains(dataType, "date") || strings.Contains(dataType, "timestamp"):
+		goType, pkg = "time.Time", "time"
+	case strings.Contains(dataType, "blob") || strings.Contains(dataType, "binary") || dataType == "bytea" || dataType == "image":
+		return "[]byte", ""
+	}
+
+	if !c.IsNullable {
+		return goType, pkg
+	}
+
+	if nullStyle == NullSQL {
+		nullTypes := map[string]string{
+			"string":    "sql.NullString",
+			"bool":      "sql.NullBool",
+			"int16":     "sql.NullInt16",
+			"int32":     "sql.NullInt32",
+			"int64":     "sql.NullInt64",
+			"float64":   "sql.NullFloat64",
+			"time.Time": "sql.NullTime",
+		}
+		if t, ok := nullTypes[goType]; ok {
+			return t, "database/sql"
+		}
+	}
+	return "*" + goType, pkg
+}
+
+var goInitialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "UUID": true, "JSON": true, "API": true,
+	"HTTP": true, "IP": true, "SQL": true, "HTML": true, "XML": true, "UTC": true,
+}
+
+// goIdentifier turns a table or column name such as user_id into an exported
+// Go name such as UserID.
+func goIdentifier(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		if goInitialisms[strings.ToUpper(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		runes := []rune(w)
+		b.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+
+	s := b.String()
+	if len(s) == 0 || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
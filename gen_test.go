@@ -0,0 +1,184 @@
+package picosql
+
+import (
+	"bytes"
+	"database/sql"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fieldLine returns the generated line declaring field, spaces collapsed.
+func fieldLine(src, field string) string {
+	for _, line := range strings.Split(src, "\n") {
+		f := strings.Fields(line)
+		if len(f) > 0 && f[0] == field {
+			return strings.Join(f, " ")
+		}
+	}
+	return ""
+}
+
+func TestGenerateStructs(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, `CREATE TABLE user_accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL,
+		age INT,
+		balance REAL NOT NULL,
+		avatar BLOB,
+		created_at DATETIME NOT NULL
+	)`)
+
+	var buf bytes.Buffer
+	if err := m.GenerateStructs(&buf, "", GenerateOptions{Package: "models", JSONTags: true}); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0); err != nil {
+		t.Fatalf("%v:\n%s", err, src)
+	}
+
+	for field, want := range map[string]string{
+		"ID":        "ID int64 `db:\"id,pk,auto\" json:\"id\"`",
+		"Email":     "Email string `db:\"email\" json:\"email\"`",
+		"Age":       "Age *int64 `db:\"age\" json:\"age\"`",
+		"Balance":   "Balance float64 `db:\"balance\" json:\"balance\"`",
+		"Avatar":    "Avatar []byte `db:\"avatar\" json:\"avatar\"`",
+		"CreatedAt": "CreatedAt time.Time `db:\"created_at\" json:\"created_at\"`",
+	} {
+		if got := fieldLine(src, field); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if !strings.Contains(src, "type UserAccounts struct") || !strings.Contains(src, `return "user_accounts"`) {
+		t.Fatalf("got:\n%s", src)
+	}
+}
+
+func TestGoFieldType(t *testing.T) {
+	tests := []struct {
+		column    ColumnDefinition
+		nullStyle string
+		dialect   string
+		want      string
+	}{
+		{ColumnDefinition{DataType: "integer"}, NullPointer, "postgres", "int32"},
+		{ColumnDefinition{DataType: "integer"}, NullPointer, "sqlite", "int64"},
+		{ColumnDefinition{DataType: "int", ColumnType: "int(10) unsigned"}, NullPointer, "mysql", "int64"},
+		{ColumnDefinition{DataType: "tinyint", ColumnType: "tinyint(1)"}, NullPointer, "mysql", "bool"},
+		{ColumnDefinition{DataType: "varchar", IsNullable: true}, NullSQL, "mysql", "sql.NullString"},
+		{ColumnDefinition{DataType: "bigint", IsNullable: true}, NullPointer, "mysql", "*int64"},
+		{ColumnDefinition{DataType: "bigint unsigned"}, NullPointer, "mysql", "uint64"},
+		{ColumnDefinition{DataType: "smallint", IsNullable: true}, NullSQL, "mysql", "sql.NullInt16"},
+	}
+	for _, tt := range tests {
+		if got, _ := goFieldType(&tt.column, tt.nullStyle, tt.dialect); got != tt.want {
+			t.Errorf("%s on %s: got %s, want %s", tt.column.DataType, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+// generatedTypes are the field types GenerateStructs writes.
+var generatedTypes = map[string]reflect.Type{
+	"string":          reflect.TypeOf(""),
+	"bool":            reflect.TypeOf(false),
+	"int16":           reflect.TypeOf(int16(0)),
+	"int32":           reflect.TypeOf(int32(0)),
+	"int64":           reflect.TypeOf(int64(0)),
+	"uint64":          reflect.TypeOf(uint64(0)),
+	"float64":         reflect.TypeOf(float64(0)),
+	"time.Time":       timeType,
+	"[]byte":          reflect.TypeOf([]byte(nil)),
+	"sql.NullString":  reflect.TypeOf(sql.NullString{}),
+	"sql.NullBool":    reflect.TypeOf(sql.NullBool{}),
+	"sql.NullInt16":   reflect.TypeOf(sql.NullInt16{}),
+	"sql.NullInt32":   reflect.TypeOf(sql.NullInt32{}),
+	"sql.NullInt64":   reflect.TypeOf(sql.NullInt64{}),
+	"sql.NullFloat64": reflect.TypeOf(sql.NullFloat64{}),
+	"sql.NullTime":    reflect.TypeOf(sql.NullTime{}),
+}
+
+// generatedStruct builds, with reflection, the first struct declared in src.
+func generatedStruct(t *testing.T, src string) reflect.Type {
+	f, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+
+		st := gd.Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+		var fields []reflect.StructField
+		for _, field := range st.Fields.List {
+			typeName := src[field.Type.Pos()-1 : field.Type.End()-1]
+			ft, ok := generatedTypes[strings.TrimPrefix(typeName, "*")]
+			if !ok {
+				t.Fatalf("unexpected field type %s", typeName)
+			}
+			if strings.HasPrefix(typeName, "*") {
+				ft = reflect.PointerTo(ft)
+			}
+
+			tag, _ := strconv.Unquote(field.Tag.Value)
+			fields = append(fields, reflect.StructField{Name: field.Names[0].Name, Type: ft, Tag: reflect.StructTag(tag)})
+		}
+		return reflect.StructOf(fields)
+	}
+	t.Fatal("no struct generated")
+	return nil
+}
+
+func TestGenerateStructsRoundTrip(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m,
+		`CREATE TABLE samples (id INTEGER PRIMARY KEY, small SMALLINT, mid INT, big BIGINT, flag BOOLEAN,
+			price DECIMAL(10,2), ratio REAL, name VARCHAR(20), born DATETIME, data BLOB, hits BIGINT UNSIGNED)`,
+		"INSERT INTO samples VALUES (1, -3, 70000, 5000000000, 1, 12.5, 0.25, 'ann', '2001-02-03 04:05:06', x'0102', 42)",
+		"INSERT INTO samples (id) VALUES (2)",
+	)
+
+	for _, dialect := range []string{"", "mysql"} {
+		for _, style := range []string{NullPointer, NullSQL} {
+			var buf bytes.Buffer
+			opts := GenerateOptions{Tables: []string{"samples"}, NullStyle: style, Dialect: dialect}
+			if err := m.GenerateStructs(&buf, "", opts); err != nil {
+				t.Fatal(err)
+			}
+
+			st := generatedStruct(t, buf.String())
+			rows := reflect.New(reflect.SliceOf(st))
+			if err := m.Select(rows.Interface(), "SELECT * FROM samples ORDER BY id"); err != nil {
+				t.Fatalf("%s %s: %v\n%s", dialect, style, err, buf.String())
+			}
+			if rows.Elem().Len() != 2 {
+				t.Fatalf("%s %s: %d rows", dialect, style, rows.Elem().Len())
+			}
+
+			full, empty := rows.Elem().Index(0), rows.Elem().Index(1)
+			born := reflect.Indirect(full.FieldByName("Born"))
+			if style == NullSQL {
+				born = born.FieldByName("Time")
+			}
+			if at, _ := born.Interface().(time.Time); at.Year() != 2001 {
+				t.Errorf("%s %s: Born = %v", dialect, style, born)
+			}
+			if !empty.FieldByName("Name").IsZero() || !empty.FieldByName("Mid").IsZero() {
+				t.Errorf("%s %s: NULL row = %v", dialect, style, empty)
+			}
+			if !reflect.DeepEqual(full.FieldByName("Data").Bytes(), []byte{1, 2}) {
+				t.Errorf("%s %s: Data = %v", dialect, style, full.FieldByName("Data"))
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package picosql
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Queryer is satisfied by *Sql and *sql.Tx, so the typed helpers below work
+// inside and outside of transactions.
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+var sharedTagMapper = make(tagMapper)
+
+func mapperFor(q Queryer) tagMapper {
+	if m, ok := q.(*Sql); ok && m.tm != nil {
+		return m.tm
+	}
+	return sharedTagMapper
+}
+
+func iterateQueryer(q Queryer, query string, args ...interface{}) (*Iterator, error) {
+	res, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(res, mapperFor(q))
+}
+
+// SelectT returns every row of query as a T. T may be a struct, a pointer to a
+// struct, map[string]interface{} or a primitive receiving the first column.
+func SelectT[T any](q Queryer, query string, args ...interface{}) ([]T, error) {
+	it, err := iterateQueryer(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var items []T
+	for it.Next() {
+		var item T
+		if err := it.Scan(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, it.Err()
+}
+
+// GetT returns the first row of query as a T, ignoring any further rows.
+func GetT[T any](q Queryer, query string, args ...interface{}) (T, error) {
+	var item T
+
+	it, err := iterateQueryer(q, query, args...)
+	if err != nil {
+		return item, err
+	}
+	defer it.Close()
+
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return item, err
+		}
+		return item, errors.New("No result in result set")
+	}
+
+	err = it.Scan(&item)
+	return item, err
+}
+
+// One is like GetT but fails when query returns more than one row.
+func One[T any](q Queryer, query string, args ...interface{}) (T, error) {
+	var item, zero T
+
+	it, err := iterateQueryer(q, query, args...)
+	if err != nil {
+		return item, err
+	}
+	defer it.Close()
+
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return item, err
+		}
+		return item, errors.New("No result in result set")
+	}
+
+	if err := it.Scan(&item); err != nil {
+		return zero, err
+	}
+
+	if it.Next() {
+		return zero, errors.New("More than one row in result set")
+	}
+	return item, it.Err()
+}
+
+// ColumnT returns the first column of every row of query.
+func ColumnT[T any](q Queryer, query string, args ...interface{}) ([]T, error) {
+	it, err := iterateQueryer(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	if len(it.columns) == 0 {
+		return nil, errors.New("No columns in result set")
+	}
+
+	var items []T
+	for it.Next() {
+		var item T
+		if err := fillTarget(it.tm, &item, it.columns[:1], it.values[:1]); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, it.Err()
+}
@@ -0,0 +1,107 @@
+package picosql
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSelectT(t *testing.T) {
+	m := newPeople(t)
+
+	people, err := SelectT[testPerson](m, "SELECT * FROM people ORDER BY id")
+	if err != nil || len(people) != 2 || people[1].Name != "bob" {
+		t.Fatalf("structs: %+v, %v", people, err)
+	}
+
+	pointers, err := SelectT[*testPerson](m, "SELECT * FROM people ORDER BY id")
+	if err != nil || len(pointers) != 2 || pointers[0].Name != "ann" {
+		t.Fatalf("pointers: %+v, %v", pointers, err)
+	}
+
+	rows, err := SelectT[map[string]interface{}](m, "SELECT name FROM people ORDER BY id")
+	if err != nil || len(rows) != 2 || rows[0]["name"] != "ann" {
+		t.Fatalf("maps: %v, %v", rows, err)
+	}
+
+	ages, err := SelectT[*int](m, "SELECT age FROM people ORDER BY id")
+	if err != nil || len(ages) != 2 || ages[0] == nil || *ages[0] != 30 || ages[1] != nil {
+		t.Fatalf("nullable primitives: %v, %v", ages, err)
+	}
+}
+
+func TestGetTAndOne(t *testing.T) {
+	m := newPeople(t)
+
+	p, err := GetT[testPerson](m, "SELECT * FROM people ORDER BY id")
+	if err != nil || p.Name != "ann" {
+		t.Fatalf("GetT: %+v, %v", p, err)
+	}
+
+	if _, err := GetT[testPerson](m, "SELECT * FROM people WHERE id = 0"); err == nil {
+		t.Error("GetT without rows returned no error")
+	}
+
+	if _, err := One[testPerson](m, "SELECT * FROM people"); err == nil {
+		t.Error("One with two rows returned no error")
+	}
+
+	n, err := One[int64](m, "SELECT COUNT(*) FROM people")
+	if err != nil || n != 2 {
+		t.Errorf("One: %d, %v", n, err)
+	}
+}
+
+func TestColumnT(t *testing.T) {
+	m := newPeople(t)
+
+	names, err := ColumnT[string](m, "SELECT name, id FROM people ORDER BY id")
+	if err != nil || !reflect.DeepEqual(names, []string{"ann", "bob"}) {
+		t.Fatalf("names: %v, %v", names, err)
+	}
+
+	mustExec(t, m, "CREATE TABLE empty (id INTEGER)")
+	if _, err := ColumnT[string](m, "DELETE FROM empty"); err == nil {
+		t.Fatal("a result without columns returned no error")
+	}
+}
+
+func TestGenericTransaction(t *testing.T) {
+	m := newPeople(t)
+	tx, err := m.CreateTransection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO people (id, name) VALUES (3, 'cy')"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ColumnT[string](tx, "SELECT name FROM people ORDER BY id")
+	if err != nil || len(names) != 3 {
+		t.Fatalf("names in transaction: %v, %v", names, err)
+	}
+}
+
+func TestTagMapperConcurrency(t *testing.T) {
+	type a struct {
+		X int `db:"x"`
+	}
+	type b struct {
+		Y int `db:"y"`
+	}
+
+	tm := make(tagMapper)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); tm.get(reflect.TypeOf(a{})) }()
+		go func() { defer wg.Done(); tm.get(reflect.TypeOf(b{})) }()
+	}
+	wg.Wait()
+
+	if tm.get(reflect.TypeOf(a{}))["x"] != "X" {
+		t.Fatalf("mapping = %v", tm)
+	}
+}
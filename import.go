@@ -0,0 +1,696 @@
+package picosql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ImportOptions struct {
+	// Format is FormatCSV or FormatNDJSON.
+	Format ExportFormat
+	Table  string
+
+	// Columns names the fields of each record. For CSV it is read from the
+	// header line when empty; for NDJSON it is the sorted set of keys found
+	// in the sampled objects.
+	Columns []string
+	// NoHeader tells that the CSV input starts with data instead of a header.
+	NoHeader bool
+	// Comma is the CSV field delimiter, ',' when zero.
+	Comma rune
+	// Null is the field value loaded as NULL. JSON nulls are always NULL.
+	Null string
+
+	// SampleSize is the number of records used to infer column types, 1000
+	// when zero. Types skips inference altogether.
+	SampleSize int
+	Types      []*ColumnTypeSimplified
+
+	// CreateTable creates Table from the inferred types before loading,
+	// with KeyField as primary key when set.
+	CreateTable bool
+	KeyField    string
+
+	// BatchSize is the number of rows per INSERT statement, 500 when zero and
+	// fewer when the placeholders of the engine run out.
+	BatchSize int
+}
+
+type RejectedRow struct {
+	Line   int
+	Reason string
+	Record []string
+}
+
+type ImportResult struct {
+	Columns  []string
+	Types    []*ColumnTypeSimplified
+	Inserted int64
+	Rejected []*RejectedRow
+}
+
+type importRecord struct {
+	line   int
+	fields []string
+	nulls  []bool
+	err    error
+}
+
+type recordReader interface {
+	read() (*importRecord, error)
+}
+
+// Import loads a CSV or NDJSON stream into a table. Column types are inferred
+// from the first records, and records that do not fit them or fail to insert
+// are reported in the result instead of aborting the import.
+func (m *Sql) Import(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	if len(opts.Table) == 0 {
+		return nil, errors.New("Import requires a table name")
+	}
+
+	if opts.SampleSize <= 0 {
+		opts.SampleSize = 1000
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	var rr recordReader
+	var sample []*importRecord
+	var err error
+
+	switch opts.Format {
+	case FormatCSV:
+		rr, err = newCSVRecordReader(r, &opts)
+		if err != nil {
+			return nil, err
+		}
+		sample, err = readSample(rr, opts.SampleSize)
+	case FormatNDJSON:
+		nr := &ndjsonRecordReader{s: bufio.NewScanner(r), null: opts.Null, columns: opts.Columns}
+		nr.s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		rr = nr
+		sample, err = nr.sample(opts.SampleSize)
+		opts.Columns = nr.columns
+	default:
+		return nil, errors.New("Unknown import format")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.Columns) == 0 {
+		return nil, errors.New("No columns to import")
+	}
+
+	// Column names come from the input, so they are checked before any SQL
+	// is built from them.
+	if _, err := m.quoteName(opts.Table); err != nil {
+		return nil, err
+	}
+	if _, err := m.quoteColumns(opts.Columns); err != nil {
+		return nil, err
+	}
+	opts.BatchSize = m.batchRows(opts.BatchSize, len(opts.Columns))
+
+	result := &ImportResult{Columns: opts.Columns}
+	for _, t := range opts.Types {
+		nt := *t
+		nt.DBType = strings.ToUpper(strings.TrimSpace(nt.DBType))
+		result.Types = append(result.Types, &nt)
+	}
+	if len(result.Types) == 0 {
+		var fields [][]string
+		var nulls [][]bool
+		for _, rec := range sample {
+			if rec.err == nil && len(rec.fields) == len(opts.Columns) {
+				fields = append(fields, rec.fields)
+				nulls = append(nulls, rec.nulls)
+			}
+		}
+		result.Types = inferColumnTypes(opts.Columns, fields, nulls)
+	}
+
+	if len(result.Types) != len(opts.Columns) {
+		return nil, fmt.Errorf("Expected %d column types, got %d", len(opts.Columns), len(result.Types))
+	}
+
+	if opts.CreateTable {
+		types := result.Types
+		if len(opts.Types) == 0 {
+			// A sample can not prove that a column never holds NULL.
+			types = make([]*ColumnTypeSimplified, len(result.Types))
+			for i, t := range result.Types {
+				nullable := *t
+				nullable.IsNullable = true
+				types[i] = &nullable
+			}
+		}
+
+		if len(opts.KeyField) > 0 {
+			err = m.CreateTable(opts.Table, opts.Columns, types, opts.KeyField)
+		} else {
+			err = m.CreateTableNoHashOrKey(opts.Table, opts.Columns, types)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var batch []*importRecord
+	var rows [][]interface{}
+
+	flush := func() {
+		if len(rows) == 0 {
+			return
+		}
+
+		if err := m.insertBatch(opts.Table, opts.Columns, rows); err == nil {
+			result.Inserted += int64(len(rows))
+		} else {
+			for i, row := range rows {
+				if err := m.insertBatch(opts.Table, opts.Columns, [][]interface{}{row}); err != nil {
+					result.reject(batch[i], err.Error())
+					continue
+				}
+				result.Inserted++
+			}
+		}
+
+		batch, rows = batch[:0], rows[:0]
+	}
+
+	add := func(rec *importRecord) {
+		if rec.err != nil {
+			result.reject(rec, rec.err.Error())
+			return
+		}
+
+		if len(rec.fields) != len(opts.Columns) {
+			result.reject(rec, fmt.Sprintf("Expected %d fields, got %d", len(opts.Columns), len(rec.fields)))
+			return
+		}
+
+		row, err := convertRecord(rec, opts.Columns, result.Types)
+		if err != nil {
+			result.reject(rec, err.Error())
+			return
+		}
+
+		batch = append(batch, rec)
+		rows = append(rows, row)
+		if len(rows) >= opts.BatchSize {
+			flush()
+		}
+	}
+
+	for _, rec := range sample {
+		add(rec)
+	}
+
+	for {
+		rec, err := rr.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		add(rec)
+	}
+
+	flush()
+	return result, nil
+}
+
+func (r *ImportResult) reject(rec *importRecord, reason string) {
+	r.Rejected = append(r.Rejected, &RejectedRow{Line: rec.line, Reason: reason, Record: rec.fields})
+}
+
+// maxParameters is the number of placeholders a statement takes on each
+// engine.
+var maxParameters = map[string]int{
+	"mysql":     65535,
+	"postgres":  65535,
+	"sqlite":    32766,
+	"sqlserver": 2100,
+}
+
+// maxInsertRows is the number of rows an INSERT ... VALUES takes, where the
+// engine limits it.
+var maxInsertRows = map[string]int{
+	"sqlserver": 1000,
+}
+
+// batchRows lowers batchSize to the rows of columns values that fit in one
+// INSERT statement.
+func (m *Sql) batchRows(batchSize, columns int) int {
+	name := m.Dialect().Name()
+	if n, ok := maxParameters[name]; ok && columns > 0 && batchSize > n/columns {
+		batchSize = n / columns
+	}
+	if n, ok := maxInsertRows[name]; ok && batchSize > n {
+		batchSize = n
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return batchSize
+}
+
+func (m *Sql) insertBatch(table string, columns []string, rows [][]interface{}) error {
+	quotedTable, err := m.quoteName(table)
+	if err != nil {
+		return err
+	}
+	quotedColumns, err := m.quoteColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + quotedTable + " (" + strings.Join(quotedColumns, ", ") + ") VALUES ")
+
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			args = append(args, v)
+			sb.WriteString(m.Dialect().Placeholder(len(args)))
+		}
+		sb.WriteString(")")
+	}
+
+	_, err = m.Exec(sb.String(), args...)
+	return err
+}
+
+func readSample(rr recordReader, n int) ([]*importRecord, error) {
+	var sample []*importRecord
+	for len(sample) < n {
+		rec, err := rr.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sample = append(sample, rec)
+	}
+	return sample, nil
+}
+
+type csvRecordReader struct {
+	r    *csv.Reader
+	null string
+}
+
+func newCSVRecordReader(r io.Reader, opts *ImportOptions) (*csvRecordReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+
+	if !opts.NoHeader {
+		header, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		if len(opts.Columns) == 0 {
+			opts.Columns = header
+		}
+	}
+
+	return &csvRecordReader{r: cr, null: opts.Null}, nil
+}
+
+func (c *csvRecordReader) read() (*importRecord, error) {
+	fields, err := c.r.Read()
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			return &importRecord{line: pe.StartLine, fields: fields, err: pe.Err}, nil
+		}
+		return nil, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	rec := &importRecord{line: line, fields: fields, nulls: make([]bool, len(fields))}
+	for i, f := range fields {
+		rec.nulls[i] = f == c.null
+	}
+	return rec, nil
+}
+
+type ndjsonRecordReader struct {
+	s       *bufio.Scanner
+	line    int
+	null    string
+	columns []string
+	pending []map[string]interface{}
+}
+
+// sample reads the first size objects and, unless columns were given,
+// derives them from the keys found.
+func (n *ndjsonRecordReader) sample(size int) ([]*importRecord, error) {
+	var objects []map[string]interface{}
+	var lines []int
+
+	for len(objects) < size {
+		obj, line, err := n.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+		lines = append(lines, line)
+	}
+
+	if len(n.columns) == 0 {
+		seen := make(map[string]bool)
+		for _, obj := range objects {
+			for k := range obj {
+				if !seen[k] {
+					seen[k] = true
+					n.columns = append(n.columns, k)
+				}
+			}
+		}
+		sort.Strings(n.columns)
+	}
+
+	sample := make([]*importRecord, len(objects))
+	for i, obj := range objects {
+		sample[i] = n.record(obj, lines[i])
+	}
+	return sample, nil
+}
+
+func (n *ndjsonRecordReader) next() (map[string]interface{}, int, error) {
+	for n.s.Scan() {
+		n.line++
+		b := bytes.TrimSpace(n.s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+
+		var obj map[string]interface{}
+		if err := d.Decode(&obj); err != nil || obj == nil {
+			return nil, n.line, nil
+		}
+		return obj, n.line, nil
+	}
+
+	if err := n.s.Err(); err != nil {
+		return nil, n.line, err
+	}
+	return nil, n.line, io.EOF
+}
+
+func (n *ndjsonRecordReader) read() (*importRecord, error) {
+	obj, line, err := n.next()
+	if err != nil {
+		return nil, err
+	}
+	return n.record(obj, line), nil
+}
+
+func (n *ndjsonRecordReader) record(obj map[string]interface{}, line int) *importRecord {
+	if obj == nil {
+		return &importRecord{line: line, err: errors.New("Invalid JSON object")}
+	}
+
+	rec := &importRecord{
+		line:   line,
+		fields: make([]string, len(n.columns)),
+		nulls:  make([]bool, len(n.columns)),
+	}
+
+	known := 0
+	for i, c := range n.columns {
+		v, ok := obj[c]
+		if !ok {
+			rec.nulls[i] = true
+			continue
+		}
+		known++
+
+		switch nv := v.(type) {
+		case nil:
+			rec.nulls[i] = true
+		case string:
+			rec.fields[i] = nv
+			rec.nulls[i] = nv == n.null && len(n.null) > 0
+		case json.Number:
+			rec.fields[i] = nv.String()
+		case bool:
+			rec.fields[i] = strconv.FormatBool(nv)
+		default:
+			b, _ := json.Marshal(nv)
+			rec.fields[i] = string(b)
+		}
+	}
+
+	if known < len(obj) {
+		for k := range obj {
+			if !containsString(n.columns, k) {
+				rec.err = fmt.Errorf("Unknown column %s", k)
+				break
+			}
+		}
+	}
+	return rec
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	importDateLayouts     = []string{"2006-01-02"}
+	importDateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"}
+)
+
+// InferColumnTypes derives a ColumnTypeSimplified for every column from sample
+// string values, as read from CSV. null marks the values to treat as NULL.
+func InferColumnTypes(columns []string, rows [][]string, null string) []*ColumnTypeSimplified {
+	nulls := make([][]bool, len(rows))
+	for i, row := range rows {
+		nulls[i] = make([]bool, len(row))
+		for j, v := range row {
+			nulls[i][j] = v == null
+		}
+	}
+	return inferColumnTypes(columns, rows, nulls)
+}
+
+func inferColumnTypes(columns []string, rows [][]string, nulls [][]bool) []*ColumnTypeSimplified {
+	types := make([]*ColumnTypeSimplified, len(columns))
+
+	for c, name := range columns {
+		isInt, isDecimal, isBool, isDate, isDateTime := true, true, true, true, true
+		var bigInt bool
+		var seen, length, intDigits, scale int
+		var nullable bool
+
+		for r, row := range rows {
+			if c >= len(row) || nulls[r][c] {
+				nullable = true
+				continue
+			}
+
+			v := strings.TrimSpace(row[c])
+			seen++
+
+			// "007" or a ZIP code would lose its leading zeros as a number
+			if hasLeadingZero(v) {
+				isInt, isDecimal = false, false
+			}
+			if l := utf8.RuneCountInString(row[c]); l > length {
+				length = l
+			}
+
+			if isInt {
+				if i, err := strconv.ParseInt(v, 10, 64); err != nil {
+					isInt = false
+				} else if i > math.MaxInt32 || i < math.MinInt32 {
+					bigInt = true
+				}
+			}
+
+			if isDecimal {
+				if d, s, ok := decimalDigits(v); ok {
+					if d > intDigits {
+						intDigits = d
+					}
+					if s > scale {
+						scale = s
+					}
+				} else {
+					isDecimal = false
+				}
+			}
+
+			if isBool {
+				lv := strings.ToLower(v)
+				isBool = lv == "true" || lv == "false"
+			}
+
+			if isDate {
+				isDate = parseAnyTime(v, importDateLayouts) != nil
+			}
+
+			if isDateTime {
+				isDateTime = parseAnyTime(v, importDateTimeLayouts) != nil
+			}
+		}
+
+		t := &ColumnTypeSimplified{Index: c, Name: name, Length: length, IsNullable: nullable || seen == 0}
+
+		switch {
+		case seen == 0:
+			t.DBType, t.ScanType = "VARCHAR", "string"
+		case isInt && bigInt:
+			t.DBType, t.ScanType = "BIGINT", "int64"
+		case isInt:
+			t.DBType, t.ScanType = "INT", "int64"
+		case isDecimal:
+			t.DBType, t.ScanType = "DECIMAL", "float64"
+			t.Precison, t.Scale = intDigits+scale, scale
+		case isBool:
+			t.DBType, t.ScanType, t.Length = "BIT", "bool", 1
+		case isDate:
+			t.DBType, t.ScanType = "DATE", "time.Time"
+		case isDateTime:
+			t.DBType, t.ScanType = "DATETIME", "time.Time"
+		default:
+			t.DBType, t.ScanType = "VARCHAR", "string"
+		}
+
+		types[c] = t
+	}
+	return types
+}
+
+// decimalDigits reports the number of integer and fractional digits of a
+// plain decimal number such as -12.345.
+func decimalDigits(v string) (int, int, bool) {
+	v = strings.TrimLeft(v, "+-")
+	if len(v) == 0 {
+		return 0, 0, false
+	}
+
+	parts := strings.SplitN(v, ".", 2)
+	for _, p := range parts {
+		for _, r := range p {
+			if r < '0' || r > '9' {
+				return 0, 0, false
+			}
+		}
+	}
+
+	if len(parts) == 1 {
+		return len(parts[0]), 0, true
+	}
+
+	if len(parts[0])+len(parts[1]) == 0 {
+		return 0, 0, false
+	}
+	return len(parts[0]), len(parts[1]), true
+}
+
+// hasLeadingZero reports whether the integer part of a number has a
+// significant leading zero, as in 007 but not in 0 or 0.5.
+func hasLeadingZero(v string) bool {
+	v = strings.TrimLeft(v, "+-")
+	return len(v) > 1 && v[0] == '0' && v[1] != '.'
+}
+
+func parseAnyTime(v string, layouts []string) *time.Time {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func convertRecord(rec *importRecord, columns []string, types []*ColumnTypeSimplified) ([]interface{}, error) {
+	row := make([]interface{}, len(columns))
+
+	for i, f := range rec.fields {
+		t := types[i]
+		if rec.nulls[i] {
+			continue
+		}
+
+		v := strings.TrimSpace(f)
+		bad := func() ([]interface{}, error) {
+			return nil, fmt.Errorf("Invalid %s value for column %s: %q", t.DBType, columns[i], f)
+		}
+
+		switch t.DBType {
+		case "INT", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return bad()
+			}
+			row[i] = n
+		case "DECIMAL", "NUMERIC":
+			if _, _, ok := decimalDigits(v); !ok {
+				return bad()
+			}
+			row[i] = v
+		case "BIT", "BOOL", "BOOLEAN":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return bad()
+			}
+			row[i] = b
+		case "DATE":
+			tv := parseAnyTime(v, importDateLayouts)
+			if tv == nil {
+				return bad()
+			}
+			row[i] = *tv
+		case "DATETIME", "TIMESTAMP":
+			tv := parseAnyTime(v, importDateTimeLayouts)
+			if tv == nil {
+				return bad()
+			}
+			row[i] = *tv
+		default:
+			row[i] = f
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,144 @@
+package picosql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE items (id INTEGER, name TEXT, price REAL)")
+	in := "id,name,price\n1,apple,1.5\n2,,2\nx,pear,3\n4,plum\n5,fig,4.25\n"
+
+	res, err := m.Import(strings.NewReader(in), ImportOptions{Table: "items", SampleSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 3 {
+		t.Errorf("Inserted = %d, want 3", res.Inserted)
+	}
+	if len(res.Rejected) != 2 || res.Rejected[0].Line != 4 || res.Rejected[1].Line != 5 {
+		t.Fatalf("Rejected = %+v", res.Rejected)
+	}
+
+	n, err := m.Count("SELECT COUNT(*) FROM items WHERE name IS NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d NULL names, want 1", n)
+	}
+}
+
+func TestImportCreateTable(t *testing.T) {
+	m := newTestDB(t)
+	in := "id,name,price\n1,apple,1.5\n2,,2\n"
+
+	res, err := m.Import(strings.NewReader(in), ImportOptions{Table: "items", CreateTable: true, KeyField: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 2 {
+		t.Errorf("Inserted = %d, want 2", res.Inserted)
+	}
+
+	ts, err := m.IntrospectTable("", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.PrimaryKey) != 1 || ts.PrimaryKey[0] != "id" {
+		t.Errorf("PrimaryKey = %v", ts.PrimaryKey)
+	}
+	for _, c := range ts.Columns {
+		if c.ColumnName != "id" && !c.IsNullable {
+			t.Errorf("column %s is NOT NULL", c.ColumnName)
+		}
+	}
+}
+
+func TestImportNDJSON(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE items (id INTEGER, name TEXT)")
+	in := `{"id": 1, "name": "apple"}
+{"id": 2, "name": null}
+not json
+`
+	res, err := m.Import(strings.NewReader(in), ImportOptions{Format: FormatNDJSON, Table: "items"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 2 || len(res.Rejected) != 1 {
+		t.Fatalf("Inserted = %d, Rejected = %+v", res.Inserted, res.Rejected)
+	}
+}
+
+func TestInferColumnTypes(t *testing.T) {
+	rows := [][]string{
+		{"1", "007", "0.5", "true", "2024-01-02", "01234", "x"},
+		{"3000000000", "12", "-10.25", "FALSE", "2024-02-03", "10001", ""},
+	}
+	types := InferColumnTypes([]string{"a", "b", "c", "d", "e", "f", "g"}, rows, "")
+
+	want := []string{"BIGINT", "VARCHAR", "DECIMAL", "BIT", "DATE", "VARCHAR", "VARCHAR"}
+	for i, tp := range types {
+		if tp.DBType != want[i] {
+			t.Errorf("column %s: %s, want %s", tp.Name, tp.DBType, want[i])
+		}
+	}
+	if types[2].Precison != 4 || types[2].Scale != 2 {
+		t.Errorf("DECIMAL(%d,%d), want DECIMAL(4,2)", types[2].Precison, types[2].Scale)
+	}
+	if types[0].IsNullable || !types[6].IsNullable {
+		t.Errorf("nullability: a %v, g %v", types[0].IsNullable, types[6].IsNullable)
+	}
+}
+
+func TestImportLowercaseTypes(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE items (id INTEGER, zip TEXT)")
+	types := []*ColumnTypeSimplified{{Name: "id", DBType: "int"}, {Name: "zip", DBType: "varchar"}}
+	res, err := m.Import(strings.NewReader("id,zip\n1,007\nx,01\n"), ImportOptions{Table: "items", Types: types})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 1 || len(res.Rejected) != 1 || res.Rejected[0].Line != 3 {
+		t.Fatalf("Inserted = %d, Rejected = %+v", res.Inserted, res.Rejected)
+	}
+	if types[0].DBType != "int" {
+		t.Errorf("caller's types were changed to %s", types[0].DBType)
+	}
+
+	zips, err := ColumnT[string](m, "SELECT zip FROM items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zips) != 1 || zips[0] != "007" {
+		t.Errorf("zips = %q", zips)
+	}
+}
+
+func TestBatchRows(t *testing.T) {
+	m := newTestDB(t)
+	if n := m.batchRows(1000, 100); n != 327 {
+		t.Errorf("sqlite: %d rows, want 327", n)
+	}
+	ss := &Sql{driver: "sqlserver"}
+	if n := ss.batchRows(5000, 1); n != 1000 {
+		t.Errorf("sqlserver: %d rows, want 1000", n)
+	}
+	if n := ss.batchRows(500, 3000); n != 1 {
+		t.Errorf("sqlserver: %d rows, want 1", n)
+	}
+}
+
+func TestImportInvalidHeader(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE items (id INTEGER)")
+	in := "id,\"x) VALUES (1); DROP TABLE items; --\"\n1,2\n"
+	if _, err := m.Import(strings.NewReader(in), ImportOptions{Table: "items"}); err == nil {
+		t.Fatal("an invalid column name was imported")
+	}
+	if _, err := m.Count("SELECT COUNT(*) FROM items"); err != nil {
+		t.Fatal(err)
+	}
+}
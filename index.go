@@ -0,0 +1,200 @@
+package picosql
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+type CreateIndexOptions struct {
+	// Database qualifies the table, the current one when empty.
+	Database string
+	// IfNotExists skips an index of the same name, found among the live
+	// indexes as MySQL has no CREATE INDEX IF NOT EXISTS.
+	IfNotExists bool
+	// SkipEquivalent skips the index when EquivalentIndex finds one under
+	// another name.
+	SkipEquivalent bool
+}
+
+// CreateIndex creates idx on table and reports whether it did. An index
+// without a name is named ix_ or ux_ followed by the table and its columns,
+// shortened with a hash past 64 characters; idx itself is left unchanged.
+// Columns may be descending or, on MySQL, prefixes of the column.
+func (m *Sql) CreateIndex(table string, idx *IndexDefinition, opts CreateIndexOptions) (bool, error) {
+	d := m.Dialect()
+	aw, err := alterWriterFor(d)
+	if err != nil {
+		return false, err
+	}
+
+	if idx.Primary {
+		return false, errors.New("Primary keys are changed with AlterTable")
+	}
+
+	if len(idx.Columns) == 0 {
+		return false, errors.New("Index has no columns")
+	}
+
+	if len(idx.Name) == 0 {
+		named := *idx
+		named.Name = defaultIndexName(d, table, idx)
+		idx = &named
+	}
+
+	if err := m.validateIndex(opts.Database, table, idx); err != nil {
+		return false, err
+	}
+
+	if opts.IfNotExists || opts.SkipEquivalent {
+		indexes, err := m.ListIndexes(opts.Database, table)
+		if err != nil {
+			return false, err
+		}
+
+		for _, live := range indexes {
+			if opts.IfNotExists && strings.EqualFold(live.Name, idx.Name) {
+				return false, nil
+			}
+			if opts.SkipEquivalent && equivalentIndex(live, idx) {
+				return false, nil
+			}
+		}
+	}
+
+	// The native clause still covers a concurrent CREATE INDEX.
+	ifNotExists := opts.IfNotExists && d.Name() != "mysql"
+	s, err := aw.createIndexSQL(quoteQualified(d, opts.Database, table), idx, ifNotExists)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := m.Exec(s); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DropIndex drops the index name of table. db may be empty for the current
+// database.
+func (m *Sql) DropIndex(db, table, name string) error {
+	d := m.Dialect()
+	aw, err := alterWriterFor(d)
+	if err != nil {
+		return err
+	}
+
+	if err := m.validateIdentifiers(db, table, name); err != nil {
+		return err
+	}
+
+	s, err := aw.dropIndexSQL(quoteQualified(d, db, table), &IndexDefinition{Name: name})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Exec(s)
+	return err
+}
+
+// ListIndexes returns the indexes of table, including its primary key.
+func (m *Sql) ListIndexes(db, table string) ([]*IndexDefinition, error) {
+	si, err := m.introspector()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateIdentifiers(db, table); err != nil {
+		return nil, err
+	}
+	return si.introspectIndexes(m, db, table)
+}
+
+// EquivalentIndex returns the index of table that serves the same lookups as
+// idx, whatever its name, or nil. It has the same columns, directions and
+// prefixes, the same INCLUDE columns in any order, and is unique when idx is.
+func (m *Sql) EquivalentIndex(db, table string, idx *IndexDefinition) (*IndexDefinition, error) {
+	indexes, err := m.ListIndexes(db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, live := range indexes {
+		if equivalentIndex(live, idx) {
+			return live, nil
+		}
+	}
+	return nil, nil
+}
+
+func equivalentIndex(live, idx *IndexDefinition) bool {
+	if idx.Unique && !live.Unique && !live.Primary {
+		return false
+	}
+
+	if len(live.Columns) != len(idx.Columns) {
+		return false
+	}
+
+	for i, c := range idx.Columns {
+		lc := live.Columns[i]
+		if !strings.EqualFold(lc.Name, c.Name) || lc.Desc != c.Desc || lc.Length != c.Length {
+			return false
+		}
+	}
+
+	if len(live.Include) != len(idx.Include) {
+		return false
+	}
+
+	include := make(map[string]bool, len(live.Include))
+	for _, c := range live.Include {
+		include[strings.ToLower(c)] = true
+	}
+	for _, c := range idx.Include {
+		if !include[strings.ToLower(c)] {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultIndexName fits the name within the identifier limit of d, and 64
+// characters, by cutting it and adding a hash of the full name.
+func defaultIndexName(d Dialect, table string, idx *IndexDefinition) string {
+	name := "ix_"
+	if idx.Unique {
+		name = "ux_"
+	}
+
+	name += table
+	for _, c := range idx.Columns {
+		name += "_" + c.Name
+	}
+
+	max := maxIdentLength[d.Name()]
+	if max == 0 || max > 64 {
+		max = 64
+	}
+	if utf8.RuneCountInString(name) <= max {
+		return name
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	return string([]rune(name)[:max-len(suffix)]) + suffix
+}
+
+func (m *Sql) validateIndex(db, table string, idx *IndexDefinition) error {
+	names := []string{db, table, idx.Name}
+	for _, c := range idx.Columns {
+		if len(c.Name) == 0 {
+			return errors.New("Index column has no name")
+		}
+		names = append(names, c.Name)
+	}
+	return m.validateIdentifiers(append(names, idx.Include...)...)
+}
@@ -0,0 +1,122 @@
+package picosql
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCreateIndex(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m, "CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT, placed TEXT)")
+
+	idx := &IndexDefinition{Columns: []IndexColumn{{Name: "customer"}, {Name: "placed", Desc: true}}}
+	created, err := m.CreateIndex("orders", idx, CreateIndexOptions{})
+	if err != nil || !created {
+		t.Fatalf("CreateIndex = %v, %v", created, err)
+	}
+	if len(idx.Name) > 0 {
+		t.Errorf("CreateIndex set the name %q of the caller's index", idx.Name)
+	}
+
+	indexes, err := m.ListIndexes("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, live := range indexes {
+		names = append(names, live.Name)
+	}
+	if got := strings.Join(names, ","); got != "PRIMARY,ix_orders_customer_placed" {
+		t.Errorf("indexes = %s", got)
+	}
+
+	created, err = m.CreateIndex("orders", idx, CreateIndexOptions{IfNotExists: true})
+	if err != nil || created {
+		t.Errorf("IfNotExists: CreateIndex = %v, %v", created, err)
+	}
+
+	same := &IndexDefinition{Name: "by_customer", Columns: idx.Columns}
+	created, err = m.CreateIndex("orders", same, CreateIndexOptions{SkipEquivalent: true})
+	if err != nil || created {
+		t.Errorf("SkipEquivalent: CreateIndex = %v, %v", created, err)
+	}
+
+	unique := &IndexDefinition{Name: "by_customer", Unique: true, Columns: idx.Columns}
+	created, err = m.CreateIndex("orders", unique, CreateIndexOptions{SkipEquivalent: true})
+	if err != nil || !created {
+		t.Errorf("unique: CreateIndex = %v, %v", created, err)
+	}
+
+	if err := m.DropIndex("", "orders", "by_customer"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []*IndexDefinition{
+		{},
+		{Primary: true, Columns: idx.Columns},
+		{Name: "x;y", Columns: idx.Columns},
+		{Columns: []IndexColumn{{Name: ""}}},
+	} {
+		if _, err := m.CreateIndex("orders", bad, CreateIndexOptions{}); err == nil {
+			t.Errorf("CreateIndex(%+v) accepted an invalid index", bad)
+		}
+	}
+}
+
+func TestEquivalentIndex(t *testing.T) {
+	columns := []IndexColumn{{Name: "a"}, {Name: "b", Desc: true}}
+	live := &IndexDefinition{Name: "live", Columns: columns, Include: []string{"c", "d"}}
+	tests := []struct {
+		idx  *IndexDefinition
+		want bool
+	}{
+		{&IndexDefinition{Columns: []IndexColumn{{Name: "A"}, {Name: "B", Desc: true}}, Include: []string{"D", "c"}}, true},
+		{&IndexDefinition{Columns: columns, Include: []string{"c"}}, false},
+		{&IndexDefinition{Columns: columns}, false},
+		{&IndexDefinition{Columns: columns, Include: []string{"c", "e"}}, false},
+		{&IndexDefinition{Columns: []IndexColumn{{Name: "a"}, {Name: "b"}}, Include: []string{"c", "d"}}, false},
+		{&IndexDefinition{Columns: columns, Include: []string{"c", "d"}, Unique: true}, false},
+	}
+	for i, tt := range tests {
+		if got := equivalentIndex(live, tt.idx); got != tt.want {
+			t.Errorf("%d: equivalentIndex = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultIndexName(t *testing.T) {
+	short := &IndexDefinition{Unique: true, Columns: []IndexColumn{{Name: "a"}, {Name: "b"}}}
+	if got := defaultIndexName(sqliteDialect{}, "t", short); got != "ux_t_a_b" {
+		t.Errorf("name = %q", got)
+	}
+
+	long := func(last string) *IndexDefinition {
+		return &IndexDefinition{Columns: []IndexColumn{
+			{Name: "customer_identifier"}, {Name: "shipping_address_line"}, {Name: last},
+		}}
+	}
+	for _, d := range []Dialect{mysqlDialect{}, postgresDialect{}, sqlServerDialect{}} {
+		a := defaultIndexName(d, "customer_orders", long("placed_at_timestamp"))
+		b := defaultIndexName(d, "customer_orders", long("placed_at_timezone"))
+		if a == b {
+			t.Errorf("%s: names collide: %q", d.Name(), a)
+		}
+		if err := ValidateIdentifier(d, a); err != nil {
+			t.Errorf("%s: %v", d.Name(), err)
+		}
+		if n := utf8.RuneCountInString(a); n > 64 || !strings.HasPrefix(a, "ix_customer_orders_customer_identifier_") {
+			t.Errorf("%s: name %q of %d characters", d.Name(), a, n)
+		}
+	}
+}
+
+func TestGroupIndexesInclude(t *testing.T) {
+	indexes := groupIndexes([]map[string]interface{}{
+		{"index_name": "ix", "column_name": "a", "is_desc": true},
+		{"index_name": "ix", "column_name": "b", "is_included": true},
+	})
+	if len(indexes) != 1 || len(indexes[0].Columns) != 1 || !indexes[0].Columns[0].Desc || strings.Join(indexes[0].Include, ",") != "b" {
+		t.Errorf("indexes = %+v", indexes[0])
+	}
+}
@@ -0,0 +1,549 @@
+package picosql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ObjectView      = "VIEW"
+	ObjectTrigger   = "TRIGGER"
+	ObjectProcedure = "PROCEDURE"
+	ObjectFunction  = "FUNCTION"
+)
+
+// DatabaseObject is a view, trigger or routine found in a database.
+type DatabaseObject struct {
+	Name       string
+	Type       string
+	Table      string // table a trigger is attached to
+	Event      string // e.g. BEFORE INSERT for triggers
+	Definition string
+}
+
+// schemaIntrospector is implemented by the dialects that support IntrospectTable
+// and the object listings.
+type schemaIntrospector interface {
+	introspectColumns(m *Sql, db, table string) ([]*ColumnDefinition, error)
+	introspectIndexes(m *Sql, db, table string) ([]*IndexDefinition, error)
+	introspectForeignKeys(m *Sql, db, table string) ([]*ForeignKeyDefinition, error)
+	listObjects(m *Sql, db, kind string) ([]*DatabaseObject, error)
+}
+
+func (m *Sql) introspector() (schemaIntrospector, error) {
+	si, ok := m.Dialect().(schemaIntrospector)
+	if !ok {
+		return nil, notSupported(m.Dialect(), "Schema introspection")
+	}
+	return si, nil
+}
+
+// IntrospectTable returns the complete structure of a table: columns with
+// nullability, defaults, auto increment, charset, collation and comments, the
+// primary key, all indexes and foreign keys. An empty db means the current
+// database or schema.
+func (m *Sql) IntrospectTable(db, table string) (*TableStructure, error) {
+	si, err := m.introspector()
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := si.introspectColumns(m, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("Table %s does not exist", table)
+	}
+
+	indexes, err := si.introspectIndexes(m, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	fks, err := si.introspectForeignKeys(m, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TableStructure{TableName: table, DatabaseName: db, Columns: columns, Indexes: indexes, ForeignKeys: fks}
+	for _, idx := range indexes {
+		if idx.Primary {
+			for _, c := range idx.Columns {
+				ts.PrimaryKey = append(ts.PrimaryKey, c.Name)
+			}
+		}
+	}
+	return ts, nil
+}
+
+func (m *Sql) ListViews(db string) ([]*DatabaseObject, error) {
+	return m.listObjects(db, ObjectView)
+}
+
+func (m *Sql) ListTriggers(db string) ([]*DatabaseObject, error) {
+	return m.listObjects(db, ObjectTrigger)
+}
+
+// ListRoutines returns the stored procedures and functions of a database.
+func (m *Sql) ListRoutines(db string) ([]*DatabaseObject, error) {
+	return m.listObjects(db, "ROUTINE")
+}
+
+func (m *Sql) listObjects(db, kind string) ([]*DatabaseObject, error) {
+	si, err := m.introspector()
+	if err != nil {
+		return nil, err
+	}
+	return si.listObjects(m, db, kind)
+}
+
+// groupIndexes builds index definitions from one row per index column, in
+// index and column order. Rows flagged is_included are INCLUDE columns.
+func groupIndexes(rows []map[string]interface{}) []*IndexDefinition {
+	var indexes []*IndexDefinition
+	var current *IndexDefinition
+	for _, r := range rows {
+		name := asString(r["index_name"])
+		if current == nil || current.Name != name {
+			current = &IndexDefinition{
+				Name:    name,
+				Unique:  asBool(r["is_unique"]),
+				Primary: asBool(r["is_primary"]),
+			}
+			indexes = append(indexes, current)
+		}
+		if asBool(r["is_included"]) {
+			current.Include = append(current.Include, asString(r["column_name"]))
+			continue
+		}
+		current.Columns = append(current.Columns, IndexColumn{
+			Name:   asString(r["column_name"]),
+			Desc:   asBool(r["is_desc"]),
+			Length: int(asInt(r["sub_part"])),
+		})
+	}
+	return indexes
+}
+
+// groupForeignKeys builds foreign key definitions from one row per column.
+func groupForeignKeys(rows []map[string]interface{}) []*ForeignKeyDefinition {
+	var fks []*ForeignKeyDefinition
+	var current *ForeignKeyDefinition
+	for _, r := range rows {
+		name := asString(r["constraint_name"])
+		if current == nil || current.Name != name {
+			current = &ForeignKeyDefinition{
+				Name:     name,
+				RefTable: asString(r["ref_table"]),
+				OnUpdate: asString(r["on_update"]),
+				OnDelete: asString(r["on_delete"]),
+			}
+			fks = append(fks, current)
+		}
+		current.Columns = append(current.Columns, asString(r["column_name"]))
+		current.RefColumns = append(current.RefColumns, asString(r["ref_column"]))
+	}
+	return fks
+}
+
+func columnsFromMaps(rows []map[string]interface{}) []*ColumnDefinition {
+	columns := make([]*ColumnDefinition, len(rows))
+	for i, r := range rows {
+		c := &ColumnDefinition{
+			ColumnName:      asString(r["column_name"]),
+			OrdinalPosition: asString(r["ordinal_position"]),
+			DataType:        asString(r["data_type"]),
+			ColumnType:      asString(r["column_type"]),
+			IsNullable:      asBool(r["is_nullable"]),
+			AutoIncrement:   asBool(r["auto_increment"]),
+			CharacterSet:    asString(r["character_set"]),
+			Collation:       asString(r["collation"]),
+			Comment:         asString(r["column_comment"]),
+		}
+		if d := r["column_default"]; d != nil {
+			def := asString(d)
+			c.Default = &def
+		}
+		columns[i] = c
+	}
+	return columns
+}
+
+func objectsFromMaps(rows []map[string]interface{}, kind string) []*DatabaseObject {
+	objects := make([]*DatabaseObject, len(rows))
+	for i, r := range rows {
+		o := &DatabaseObject{
+			Name:       asString(r["name"]),
+			Type:       strings.ToUpper(asString(r["type"])),
+			Table:      asString(r["table_name"]),
+			Event:      asString(r["event"]),
+			Definition: asString(r["definition"]),
+		}
+		if len(o.Type) == 0 {
+			o.Type = kind
+		}
+		objects[i] = o
+	}
+	return objects
+}
+
+func asString(v interface{}) string {
+	switch nv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return nv
+	case []byte:
+		return string(nv)
+	}
+	return fmt.Sprint(v)
+}
+
+func asInt(v interface{}) int64 {
+	switch nv := v.(type) {
+	case int64:
+		return nv
+	case int:
+		return int64(nv)
+	case int32:
+		return int64(nv)
+	case uint64:
+		return int64(nv)
+	case float64:
+		return int64(nv)
+	case bool:
+		if nv {
+			return 1
+		}
+		return 0
+	}
+	i, _ := strconv.ParseInt(strings.TrimSpace(asString(v)), 10, 64)
+	return i
+}
+
+func asBool(v interface{}) bool {
+	switch nv := v.(type) {
+	case bool:
+		return nv
+	case nil:
+		return false
+	case string, []byte:
+		switch strings.ToUpper(strings.TrimSpace(asString(nv))) {
+		case "1", "YES", "Y", "TRUE", "T":
+			return true
+		}
+		return false
+	}
+	return asInt(v) != 0
+}
+
+// MySQL
+
+func (mysqlDialect) introspectColumns(m *Sql, db, table string) ([]*ColumnDefinition, error) {
+	rows, err := m.Maps(`SELECT COLUMN_NAME AS column_name, ORDINAL_POSITION AS ordinal_position,
+		DATA_TYPE AS data_type, COLUMN_TYPE AS column_type, IS_NULLABLE = 'YES' AS is_nullable,
+		COLUMN_DEFAULT AS column_default, EXTRA LIKE '%auto_increment%' AS auto_increment,
+		EXTRA LIKE '%DEFAULT_GENERATED%' AS default_generated,
+		CHARACTER_SET_NAME AS character_set, COLLATION_NAME AS collation, COLUMN_COMMENT AS column_comment
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := columnsFromMaps(rows)
+	for i, c := range columns {
+		if c.Default != nil {
+			def := mysqlDefault(*c.Default, asBool(rows[i]["default_generated"]))
+			c.Default = &def
+		}
+	}
+	return columns, nil
+}
+
+// mysqlDefault turns a COLUMN_DEFAULT of MySQL into an SQL expression. MySQL
+// reports literal defaults unquoted, and expression defaults without the
+// parentheses they are declared with. MariaDB reports them as expressions
+// already.
+func mysqlDefault(def string, generated bool) string {
+	upper := strings.ToUpper(def)
+	switch {
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "NOW("):
+		return def
+	case generated:
+		return "(" + def + ")"
+	case strings.HasPrefix(def, "'"), upper == "NULL", strings.HasPrefix(upper, "B'"), isJSONNumber([]byte(def)):
+		return def
+	}
+	return mysqlDialect{}.QuoteLiteral(def)
+}
+
+func (mysqlDialect) introspectIndexes(m *Sql, db, table string) ([]*IndexDefinition, error) {
+	rows, err := m.Maps(`SELECT INDEX_NAME AS index_name, NON_UNIQUE = 0 AS is_unique, INDEX_NAME = 'PRIMARY' AS is_primary,
+		COLUMN_NAME AS column_name, COLLATION = 'D' AS is_desc, SUB_PART AS sub_part
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return groupIndexes(rows), nil
+}
+
+func (mysqlDialect) introspectForeignKeys(m *Sql, db, table string) ([]*ForeignKeyDefinition, error) {
+	rows, err := m.Maps(`SELECT k.CONSTRAINT_NAME AS constraint_name, k.COLUMN_NAME AS column_name,
+		k.REFERENCED_TABLE_NAME AS ref_table, k.REFERENCED_COLUMN_NAME AS ref_column,
+		r.UPDATE_RULE AS on_update, r.DELETE_RULE AS on_delete
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+		WHERE k.TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND k.TABLE_NAME = ?
+			AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return groupForeignKeys(rows), nil
+}
+
+func (mysqlDialect) listObjects(m *Sql, db, kind string) ([]*DatabaseObject, error) {
+	var q string
+	switch kind {
+	case ObjectView:
+		q = `SELECT TABLE_NAME AS name, VIEW_DEFINITION AS definition FROM information_schema.VIEWS
+			WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) ORDER BY TABLE_NAME`
+	case ObjectTrigger:
+		q = `SELECT TRIGGER_NAME AS name, EVENT_OBJECT_TABLE AS table_name,
+			CONCAT(ACTION_TIMING, ' ', EVENT_MANIPULATION) AS event, ACTION_STATEMENT AS definition
+			FROM information_schema.TRIGGERS
+			WHERE TRIGGER_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) ORDER BY TRIGGER_NAME`
+	default:
+		q = `SELECT ROUTINE_NAME AS name, ROUTINE_TYPE AS type, ROUTINE_DEFINITION AS definition
+			FROM information_schema.ROUTINES
+			WHERE ROUTINE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) ORDER BY ROUTINE_NAME`
+	}
+
+	rows, err := m.Maps(q, db)
+	if err != nil {
+		return nil, err
+	}
+	return objectsFromMaps(rows, kind), nil
+}
+
+// PostgreSQL, where db names the schema.
+
+const postgresSchema = "COALESCE(NULLIF($1, ''), current_schema())"
+
+func (postgresDialect) introspectColumns(m *Sql, db, table string) ([]*ColumnDefinition, error) {
+	rows, err := m.Maps(`SELECT column_name, ordinal_position, data_type,
+		CASE
+			WHEN character_maximum_length IS NOT NULL THEN data_type || '(' || character_maximum_length || ')'
+			WHEN data_type = 'numeric' AND numeric_precision IS NOT NULL THEN data_type || '(' || numeric_precision || ',' || numeric_scale || ')'
+			ELSE data_type
+		END AS column_type,
+		is_nullable = 'YES' AS is_nullable, column_default,
+		(is_identity = 'YES' OR COALESCE(column_default, '') LIKE 'nextval(%') AS auto_increment,
+		character_set_name AS character_set, collation_name AS collation,
+		col_description((quote_ident(table_schema) || '.' || quote_ident(table_name))::regclass, ordinal_position::int) AS column_comment
+		FROM information_schema.columns
+		WHERE table_schema = `+postgresSchema+` AND table_name = $2
+		ORDER BY ordinal_position`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return columnsFromMaps(rows), nil
+}
+
+func (postgresDialect) introspectIndexes(m *Sql, db, table string) ([]*IndexDefinition, error) {
+	rows, err := m.Maps(`SELECT i.relname AS index_name, ix.indisunique AS is_unique, ix.indisprimary AS is_primary,
+		a.attname AS column_name, (ix.indoption[k.ord - 1] & 1) = 1 AS is_desc, 0 AS sub_part,
+		k.ord > ix.indnkeyatts AS is_included
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = `+postgresSchema+` AND t.relname = $2
+		ORDER BY ix.indisprimary DESC, i.relname, k.ord`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return groupIndexes(rows), nil
+}
+
+var postgresForeignKeyActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+func (postgresDialect) introspectForeignKeys(m *Sql, db, table string) ([]*ForeignKeyDefinition, error) {
+	rows, err := m.Maps(`SELECT c.conname AS constraint_name, a.attname AS column_name,
+		rt.relname AS ref_table, ra.attname AS ref_column,
+		c.confupdtype::text AS on_update, c.confdeltype::text AS on_delete
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+		WHERE c.contype = 'f' AND n.nspname = `+postgresSchema+` AND t.relname = $2
+		ORDER BY c.conname, k.ord`, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	fks := groupForeignKeys(rows)
+	for _, fk := range fks {
+		fk.OnUpdate = postgresForeignKeyActions[fk.OnUpdate]
+		fk.OnDelete = postgresForeignKeyActions[fk.OnDelete]
+	}
+	return fks, nil
+}
+
+func (postgresDialect) listObjects(m *Sql, db, kind string) ([]*DatabaseObject, error) {
+	var q string
+	switch kind {
+	case ObjectView:
+		q = `SELECT table_name AS name, view_definition AS definition FROM information_schema.views
+			WHERE table_schema = ` + postgresSchema + ` ORDER BY table_name`
+	case ObjectTrigger:
+		q = `SELECT trigger_name AS name, event_object_table AS table_name,
+			string_agg(action_timing || ' ' || event_manipulation, ', ') AS event, min(action_statement) AS definition
+			FROM information_schema.triggers
+			WHERE trigger_schema = ` + postgresSchema + `
+			GROUP BY trigger_name, event_object_table ORDER BY trigger_name`
+	default:
+		q = `SELECT routine_name AS name, routine_type AS type, routine_definition AS definition
+			FROM information_schema.routines
+			WHERE specific_schema = ` + postgresSchema + ` ORDER BY routine_name`
+	}
+
+	rows, err := m.Maps(q, db)
+	if err != nil {
+		return nil, err
+	}
+	return objectsFromMaps(rows, kind), nil
+}
+
+// SQLite, where db names an attached schema such as main.
+
+func sqliteSchema(db string) string {
+	if len(db) == 0 {
+		return "main"
+	}
+	return db
+}
+
+func (sqliteDialect) introspectColumns(m *Sql, db, table string) ([]*ColumnDefinition, error) {
+	rows, err := m.Maps(`SELECT name AS column_name, cid + 1 AS ordinal_position,
+		lower(CASE WHEN instr(type, '(') > 0 THEN substr(type, 1, instr(type, '(') - 1) ELSE type END) AS data_type,
+		lower(type) AS column_type, "notnull" = 0 AND pk = 0 AS is_nullable, dflt_value AS column_default, pk
+		FROM pragma_table_info(?, ?)
+		ORDER BY cid`, table, sqliteSchema(db))
+	if err != nil {
+		return nil, err
+	}
+
+	columns := columnsFromMaps(rows)
+
+	// An INTEGER PRIMARY KEY is an alias of the rowid and increments on its own.
+	var pks []int
+	for i, r := range rows {
+		if asInt(r["pk"]) > 0 {
+			pks = append(pks, i)
+		}
+	}
+	if len(pks) == 1 && columns[pks[0]].ColumnType == "integer" {
+		columns[pks[0]].AutoIncrement = true
+	}
+	return columns, nil
+}
+
+func (sqliteDialect) introspectIndexes(m *Sql, db, table string) ([]*IndexDefinition, error) {
+	list, err := m.Maps(`SELECT name, "unique" AS is_unique, origin FROM pragma_index_list(?, ?) ORDER BY name`, table, sqliteSchema(db))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*IndexDefinition
+	for _, l := range list {
+		idx := &IndexDefinition{
+			Name:    asString(l["name"]),
+			Unique:  asBool(l["is_unique"]),
+			Primary: asString(l["origin"]) == "pk",
+		}
+
+		columns, err := m.Maps(`SELECT name, "desc" AS is_desc FROM pragma_index_xinfo(?, ?) WHERE key = 1 ORDER BY seqno`, idx.Name, sqliteSchema(db))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range columns {
+			idx.Columns = append(idx.Columns, IndexColumn{Name: asString(c["name"]), Desc: asBool(c["is_desc"])})
+		}
+		indexes = append(indexes, idx)
+	}
+
+	// A rowid primary key has no index of its own.
+	pk, err := m.Maps(`SELECT name FROM pragma_table_info(?, ?) WHERE pk > 0 ORDER BY pk`, table, sqliteSchema(db))
+	if err != nil {
+		return nil, err
+	}
+
+	hasPrimary := false
+	for _, idx := range indexes {
+		hasPrimary = hasPrimary || idx.Primary
+	}
+
+	if !hasPrimary && len(pk) > 0 {
+		idx := &IndexDefinition{Name: "PRIMARY", Unique: true, Primary: true}
+		for _, c := range pk {
+			idx.Columns = append(idx.Columns, IndexColumn{Name: asString(c["name"])})
+		}
+		indexes = append(indexes, idx)
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return indexes[i].Primary && !indexes[j].Primary
+	})
+	return indexes, nil
+}
+
+func (sqliteDialect) introspectForeignKeys(m *Sql, db, table string) ([]*ForeignKeyDefinition, error) {
+	rows, err := m.Maps(`SELECT 'fk_' || id AS constraint_name, "from" AS column_name, "table" AS ref_table,
+		"to" AS ref_column, on_update, on_delete
+		FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`, table, sqliteSchema(db))
+	if err != nil {
+		return nil, err
+	}
+	return groupForeignKeys(rows), nil
+}
+
+func (d sqliteDialect) listObjects(m *Sql, db, kind string) ([]*DatabaseObject, error) {
+	if kind != ObjectView && kind != ObjectTrigger {
+		return nil, nil
+	}
+
+	master := d.QuoteIdent(sqliteSchema(db)) + ".sqlite_master"
+	rows, err := m.Maps(`SELECT name, tbl_name AS table_name, sql AS definition FROM `+master+` WHERE type = ? ORDER BY name`, strings.ToLower(kind))
+	if err != nil {
+		return nil, err
+	}
+
+	objects := objectsFromMaps(rows, kind)
+	if kind == ObjectView {
+		for _, o := range objects {
+			o.Table = ""
+		}
+	}
+	return objects, nil
+}
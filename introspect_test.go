@@ -0,0 +1,107 @@
+package picosql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newSchema(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, email VARCHAR(100) NOT NULL, note TEXT DEFAULT 'none')",
+		"CREATE UNIQUE INDEX customers_email ON customers (email DESC)",
+		"CREATE TABLE orders (id INTEGER, line INTEGER, customer_id INTEGER REFERENCES customers (id) ON DELETE CASCADE, PRIMARY KEY (id, line))",
+		"CREATE VIEW big_orders AS SELECT * FROM orders WHERE line > 10",
+		"CREATE TRIGGER orders_touch AFTER INSERT ON orders BEGIN SELECT 1; END",
+	)
+	return m
+}
+
+func TestIntrospectTable(t *testing.T) {
+	m := newSchema(t)
+
+	ts, err := m.IntrospectTable("", "customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ts.PrimaryKey, []string{"id"}) {
+		t.Errorf("PrimaryKey = %v", ts.PrimaryKey)
+	}
+
+	id, email, note := ts.Columns[0], ts.Columns[1], ts.Columns[2]
+	if !id.AutoIncrement || id.IsNullable {
+		t.Errorf("id = %+v", id)
+	}
+	if email.IsNullable || email.ColumnType != "varchar(100)" || email.DataType != "varchar" {
+		t.Errorf("email = %+v", email)
+	}
+	if !note.IsNullable || note.Default == nil || *note.Default != "'none'" {
+		t.Errorf("note = %+v", note)
+	}
+
+	var unique *IndexDefinition
+	for _, idx := range ts.Indexes {
+		if idx.Name == "customers_email" {
+			unique = idx
+		}
+	}
+	if unique == nil || !unique.Unique || !reflect.DeepEqual(unique.Columns, []IndexColumn{{Name: "email", Desc: true}}) {
+		t.Errorf("Indexes = %+v", ts.Indexes)
+	}
+
+	ts, err = m.IntrospectTable("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ts.PrimaryKey, []string{"id", "line"}) {
+		t.Errorf("PrimaryKey = %v", ts.PrimaryKey)
+	}
+	if len(ts.ForeignKeys) != 1 {
+		t.Fatalf("ForeignKeys = %+v", ts.ForeignKeys)
+	}
+	fk := ts.ForeignKeys[0]
+	if fk.RefTable != "customers" || !reflect.DeepEqual(fk.Columns, []string{"customer_id"}) || fk.OnDelete != "CASCADE" {
+		t.Errorf("foreign key = %+v", fk)
+	}
+
+	if _, err := m.IntrospectTable("", "missing"); err == nil {
+		t.Error("a missing table was introspected")
+	}
+}
+
+func TestGetCurrentStructure(t *testing.T) {
+	m := newSchema(t)
+	ts, err := m.GetCurrentStructure("", "customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.Columns) != 3 {
+		t.Fatalf("Columns = %+v", ts.Columns)
+	}
+	if email := ts.Columns[1]; email.IsNullable || email.OrdinalPosition != "2" {
+		t.Errorf("email = %+v", email)
+	}
+	if note := ts.Columns[2]; !note.IsNullable || note.Default == nil {
+		t.Errorf("note = %+v", note)
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	m := newSchema(t)
+
+	views, err := m.ListViews("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 1 || views[0].Name != "big_orders" || views[0].Type != ObjectView {
+		t.Errorf("views = %+v", views)
+	}
+
+	triggers, err := m.ListTriggers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers) != 1 || triggers[0].Name != "orders_touch" || triggers[0].Table != "orders" {
+		t.Errorf("triggers = %+v", triggers)
+	}
+}
@@ -0,0 +1,98 @@
+package picosql
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Iterator walks a result set one row at a time instead of materialising it
+// like Select and Maps do. It closes the underlying rows once Next returns
+// false, and Close may always be deferred safely.
+type Iterator struct {
+	rows    *sql.Rows
+	tm      tagMapper
+	columns []string
+	values  []interface{}
+	err     error
+	closed  bool
+}
+
+func newIterator(rows *sql.Rows, tm tagMapper) (*Iterator, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &Iterator{rows: rows, tm: tm, columns: columns}, nil
+}
+
+func (m *Sql) Iterate(query string, args ...interface{}) (*Iterator, error) {
+	m.open()
+
+	if !m.IsOpen {
+		return nil, connectionError
+	}
+
+	res, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIterator(res, m.tm)
+}
+
+func (it *Iterator) Next() bool {
+	if it.closed {
+		return false
+	}
+
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		it.Close()
+		return false
+	}
+
+	values, err := scanValues(it.rows, len(it.columns))
+	if err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+
+	it.values = values
+	return true
+}
+
+// Scan copies the current row into target, which may be a pointer to a struct
+// (mapped by db tags like Select), a pointer to a pointer to a struct, a map
+// or pointer to map[string]interface{}, or a pointer to a primitive which
+// receives the first column.
+func (it *Iterator) Scan(target interface{}) error {
+	if it.values == nil {
+		return errors.New("Scan called without a successful Next")
+	}
+	return fillTarget(it.tm, target, it.columns, it.values)
+}
+
+func (it *Iterator) Columns() []string {
+	return it.columns
+}
+
+// Values returns the raw values of the current row as returned by the driver.
+func (it *Iterator) Values() []interface{} {
+	return it.values
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Close() error {
+	if it.closed {
+		return nil
+	}
+
+	it.closed = true
+	it.values = nil
+	return it.rows.Close()
+}
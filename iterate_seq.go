@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package picosql
+
+import "iter"
+
+// IterateT streams the rows of query as values of T. The rows are closed when
+// the loop finishes, breaks early or an error is yielded.
+//
+//	for user, err := range picosql.IterateT[User](db, "SELECT * FROM users") {
+//		...
+//	}
+func IterateT[T any](q Queryer, query string, args ...interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		it, err := iterateQueryer(q, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer it.Close()
+
+		for it.Next() {
+			var item T
+			if err := it.Scan(&item); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if err := it.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
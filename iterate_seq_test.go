@@ -0,0 +1,32 @@
+//go:build go1.23
+
+package picosql
+
+import "testing"
+
+func TestIterateT(t *testing.T) {
+	m := newPeople(t)
+
+	var names []string
+	for p, err := range IterateT[testPerson](m, "SELECT * FROM people ORDER BY id") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, p.Name)
+		break
+	}
+	if len(names) != 1 || names[0] != "ann" {
+		t.Fatalf("names = %v", names)
+	}
+
+	// Breaking out of the loop closed the rows, so the connection is free.
+	if _, err := m.Count("SELECT COUNT(*) FROM people"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range IterateT[testPerson](m, "SELECT * FROM missing") {
+		if err == nil {
+			t.Fatal("a failing query yielded no error")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package picosql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPerson struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"name"`
+	Nickname *string        `db:"nickname"`
+	Age      sql.NullInt64  `db:"age"`
+	Score    int16          `db:"score"`
+	Visits   uint64         `db:"visits"`
+	Born     *time.Time     `db:"born"`
+	Email    sql.NullString `db:"email"`
+}
+
+func newPeople(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE people (id INTEGER, name TEXT, nickname TEXT, age INTEGER, score INTEGER, visits INTEGER, born DATETIME, email TEXT)",
+		"INSERT INTO people VALUES (1, 'ann', 'annie', 30, 7, 12, '1990-05-01 10:00:00', 'ann@example.com')",
+		"INSERT INTO people VALUES (2, 'bob', NULL, NULL, -3, 0, NULL, NULL)",
+	)
+	return m
+}
+
+func TestIterate(t *testing.T) {
+	m := newPeople(t)
+	it, err := m.Iterate("SELECT * FROM people ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var people []testPerson
+	for it.Next() {
+		var p testPerson
+		if err := it.Scan(&p); err != nil {
+			t.Fatal(err)
+		}
+		people = append(people, p)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(people) != 2 {
+		t.Fatalf("got %d rows", len(people))
+	}
+	ann, bob := people[0], people[1]
+	if ann.Nickname == nil || *ann.Nickname != "annie" || ann.Age.Int64 != 30 || ann.Score != 7 || ann.Visits != 12 || !ann.Email.Valid {
+		t.Errorf("ann = %+v", ann)
+	}
+	if ann.Born == nil || ann.Born.Year() != 1990 {
+		t.Errorf("ann.Born = %v", ann.Born)
+	}
+	if bob.Nickname != nil || bob.Age.Valid || bob.Score != -3 || bob.Born != nil || bob.Email.Valid {
+		t.Errorf("bob = %+v", bob)
+	}
+}
+
+func TestIterateTargets(t *testing.T) {
+	m := newPeople(t)
+	it, err := m.Iterate("SELECT name, age FROM people ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+
+	var name string
+	if err := it.Scan(&name); err != nil || name != "ann" {
+		t.Errorf("primitive: %q, %v", name, err)
+	}
+
+	row := map[string]interface{}{}
+	if err := it.Scan(row); err != nil || row["name"] != "ann" {
+		t.Errorf("map: %v, %v", row, err)
+	}
+
+	var p *testPerson
+	if err := it.Scan(&p); err != nil || p == nil || p.Name != "ann" {
+		t.Errorf("pointer to struct: %+v, %v", p, err)
+	}
+
+	if err := it.Scan(testPerson{}); err == nil {
+		t.Error("a non-pointer target was scanned")
+	}
+}
+
+func TestIterateEarlyClose(t *testing.T) {
+	m := newPeople(t)
+	it, err := m.Iterate("SELECT * FROM people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if it.Next() {
+		t.Fatal("Next after Close returned a row")
+	}
+
+	// With a single connection this blocks if the rows were left open.
+	if _, err := m.Count("SELECT COUNT(*) FROM people"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSelectNullableFields(t *testing.T) {
+	m := newPeople(t)
+	var people []*testPerson
+	if err := m.Select(&people, "SELECT * FROM people ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 2 || people[0].Nickname == nil || people[1].Nickname != nil || people[1].Age.Valid {
+		t.Fatalf("people = %+v", people)
+	}
+
+	var names []string
+	if err := m.Select(&names, "SELECT name FROM people ORDER BY id"); err != nil || !reflect.DeepEqual(names, []string{"ann", "bob"}) {
+		t.Fatalf("names = %v, %v", names, err)
+	}
+
+	var bad []struct {
+		Score int8 `db:"score"`
+	}
+	if err := m.Select(&bad, "SELECT 1000 AS score"); err == nil {
+		t.Fatal("an overflowing value was scanned")
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	var (
+		s   string
+		b   bool
+		i8  int8
+		u32 uint32
+		f   float32
+		raw []byte
+		p   *int
+		tm  time.Time
+		ns  sql.NullString
+	)
+	tests := []struct {
+		field interface{}
+		value interface{}
+		want  interface{}
+	}{
+		{&s, []byte("text"), "text"},
+		{&s, int64(5), "5"},
+		{&b, []byte("1"), true},
+		{&b, int64(0), false},
+		{&i8, []byte("-12"), int8(-12)},
+		{&u32, int64(7), uint32(7)},
+		{&f, []byte("1.5"), float32(1.5)},
+		{&raw, []byte{1, 2}, []byte{1, 2}},
+		{&tm, []byte("2020-01-02 03:04:05"), time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{&ns, "x", sql.NullString{String: "x", Valid: true}},
+	}
+	for _, tt := range tests {
+		v := reflect.ValueOf(tt.field).Elem()
+		if err := setValue(v, tt.value); err != nil {
+			t.Errorf("%T from %v: %v", tt.field, tt.value, err)
+			continue
+		}
+		if got := v.Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%T from %v: got %v, want %v", tt.field, tt.value, got, tt.want)
+		}
+	}
+
+	if err := setValue(reflect.ValueOf(&p).Elem(), int64(3)); err != nil || p == nil || *p != 3 {
+		t.Errorf("pointer: %v, %v", p, err)
+	}
+	if err := setValue(reflect.ValueOf(&p).Elem(), nil); err != nil || p != nil {
+		t.Errorf("NULL pointer: %v, %v", p, err)
+	}
+	if err := setValue(reflect.ValueOf(&u32).Elem(), int64(-1)); err == nil {
+		t.Error("a negative value was stored in a uint32")
+	}
+	if err := setValue(reflect.ValueOf(&i8).Elem(), "abc"); err == nil {
+		t.Error("text was stored in an int8")
+	}
+}
@@ -0,0 +1,295 @@
+package picosql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type KeysetOptions struct {
+	Keys   []string // result columns that order and uniquely identify a row
+	Limit  int      // rows per page
+	Cursor string   // token from a previous KeysetPage, empty for the first page
+	Desc   bool     // walk the keys in descending order
+}
+
+type KeysetPage struct {
+	Next    string
+	Prev    string
+	HasNext bool
+	HasPrev bool
+}
+
+type keysetCursor struct {
+	Backward bool          `json:"b,omitempty"`
+	Keys     []cursorValue `json:"k"`
+}
+
+type cursorValue struct {
+	Type  string      `json:"t"`
+	Value interface{} `json:"v"`
+}
+
+// SelectKeyset appends one page of query to targets, a pointer to a slice of
+// structs, using keyset pagination on opts.Keys. query is wrapped as a derived
+// table so it may contain its own WHERE clause and placeholders.
+func (m *Sql) SelectKeyset(targets interface{}, opts KeysetOptions, query string, args ...interface{}) (*KeysetPage, error) {
+	if len(opts.Keys) == 0 {
+		return nil, errors.New("Keyset pagination requires at least one key column")
+	}
+
+	if opts.Limit <= 0 {
+		return nil, errors.New("Limit must be greater than zero")
+	}
+
+	v := reflect.ValueOf(targets)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("Target must be a pointer to a slice")
+	}
+
+	keys, err := m.quoteColumns(opts.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor keysetCursor
+	if len(opts.Cursor) > 0 {
+		c, err := decodeKeysetCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.Keys) != len(opts.Keys) {
+			return nil, errors.New("Cursor does not match key columns")
+		}
+		cursor = *c
+	}
+
+	descending := opts.Desc != cursor.Backward
+	op, order := ">", " ASC"
+	if descending {
+		op, order = "<", " DESC"
+	}
+
+	q := "SELECT * FROM (" + query + ") AS picosql_keyset"
+	params := append([]interface{}{}, args...)
+
+	if len(cursor.Keys) > 0 {
+		values := make([]interface{}, len(cursor.Keys))
+		for i, k := range cursor.Keys {
+			values[i] = k.Value
+		}
+		where, whereArgs := m.keysetCondition(keys, values, op, len(params))
+		q += " WHERE " + where
+		params = append(params, whereArgs...)
+	}
+
+	orderBy := make([]string, len(keys))
+	for i, k := range keys {
+		orderBy[i] = k + order
+	}
+	q = m.Dialect().LimitOffset(q+" ORDER BY "+strings.Join(orderBy, ", "), opts.Limit+1, 0)
+
+	page := reflect.New(v.Elem().Type())
+	if err := m.Select(page.Interface(), q, params...); err != nil {
+		return nil, err
+	}
+
+	rows := page.Elem()
+	hasMore := rows.Len() > opts.Limit
+	if hasMore {
+		rows = rows.Slice(0, opts.Limit)
+	}
+
+	if cursor.Backward {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	v.Elem().Set(reflect.AppendSlice(v.Elem(), rows))
+
+	result := &KeysetPage{}
+	if cursor.Backward {
+		result.HasNext = len(cursor.Keys) > 0
+		result.HasPrev = hasMore
+	} else {
+		result.HasNext = hasMore
+		result.HasPrev = len(cursor.Keys) > 0
+	}
+
+	if rows.Len() == 0 {
+		return result, nil
+	}
+
+	if result.HasNext {
+		if result.Next, err = m.encodeKeysetCursor(rows.Index(rows.Len()-1), opts.Keys, false); err != nil {
+			return nil, err
+		}
+	}
+	if result.HasPrev {
+		if result.Prev, err = m.encodeKeysetCursor(rows.Index(0), opts.Keys, true); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// keysetCondition builds "(k1, k2) > (?, ?)", or the equivalent expanded form
+// for engines without row value comparison. keys are quoted column names and
+// offset is the number of arguments that already precede the condition.
+func (m *Sql) keysetCondition(keys []string, values []interface{}, op string, offset int) (string, []interface{}) {
+	var args []interface{}
+	next := func(v interface{}) string {
+		args = append(args, v)
+		return m.Dialect().Placeholder(offset + len(args))
+	}
+
+	if m.Dialect().SupportsRowValues() {
+		vars := make([]string, len(keys))
+		for i := range keys {
+			vars[i] = next(values[i])
+		}
+		return "(" + strings.Join(keys, ", ") + ") " + op + " (" + strings.Join(vars, ", ") + ")", args
+	}
+
+	var ors []string
+	for i := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j]+" = "+next(values[j]))
+		}
+		ands = append(ands, keys[i]+" "+op+" "+next(values[i]))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func (m *Sql) encodeKeysetCursor(row reflect.Value, keys []string, backward bool) (string, error) {
+	for row.Kind() == reflect.Ptr {
+		row = row.Elem()
+	}
+
+	if row.Kind() != reflect.Struct {
+		return "", errors.New("Keyset pagination requires a slice of structs")
+	}
+
+	tm := m.tm.get(row.Type())
+	cursor := keysetCursor{Backward: backward}
+	for _, k := range keys {
+		fn, ok := tm[k]
+		if !ok {
+			return "", fmt.Errorf("Key column %s is not mapped in %s", k, row.Type().Name())
+		}
+		cv, ok := newCursorValue(row.FieldByName(fn))
+		if !ok {
+			return "", fmt.Errorf("Key column %s is NULL, which keyset pagination can not compare", k)
+		}
+		cursor.Keys = append(cursor.Keys, cv)
+	}
+
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeKeysetCursor(token string) (*keysetCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var cursor keysetCursor
+	if err := d.Decode(&cursor); err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+
+	for i := range cursor.Keys {
+		if err := cursor.Keys[i].decode(); err != nil {
+			return nil, err
+		}
+	}
+	return &cursor, nil
+}
+
+// newCursorValue returns the cursor value of a key field, false when it is
+// NULL: "k > NULL" matches no row, so NULL keys can not be paginated.
+func newCursorValue(f reflect.Value) (cursorValue, bool) {
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return cursorValue{}, false
+		}
+		f = f.Elem()
+	}
+
+	if v, ok := f.Interface().(driver.Valuer); ok {
+		dv, err := v.Value()
+		if err != nil || dv == nil {
+			return cursorValue{}, false
+		}
+		f = reflect.ValueOf(dv)
+	}
+
+	if t, ok := f.Interface().(time.Time); ok {
+		return cursorValue{Type: "t", Value: t.Format(time.RFC3339Nano)}, true
+	}
+
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: "i", Value: f.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: "u", Value: f.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: "f", Value: f.Float()}, true
+	case reflect.Bool:
+		return cursorValue{Type: "b", Value: f.Bool()}, true
+	case reflect.String:
+		return cursorValue{Type: "s", Value: f.String()}, true
+	case reflect.Slice:
+		if f.Type().Elem().Kind() == reflect.Uint8 {
+			return cursorValue{Type: "s", Value: string(f.Bytes())}, true
+		}
+	}
+	return cursorValue{Type: "s", Value: fmt.Sprint(f.Interface())}, true
+}
+
+func (c *cursorValue) decode() error {
+	var err error
+	switch c.Type {
+	case "i":
+		n, _ := c.Value.(json.Number)
+		c.Value, err = n.Int64()
+	case "u":
+		n, _ := c.Value.(json.Number)
+		c.Value, err = strconv.ParseUint(n.String(), 10, 64)
+	case "f":
+		n, _ := c.Value.(json.Number)
+		c.Value, err = n.Float64()
+	case "t":
+		s, _ := c.Value.(string)
+		c.Value, err = time.Parse(time.RFC3339Nano, s)
+	case "b", "s":
+		if c.Value == nil {
+			err = errors.New("NULL key value")
+		}
+	default:
+		err = errors.New("Invalid cursor")
+	}
+
+	if err != nil {
+		return errors.New("Invalid cursor")
+	}
+	return nil
+}
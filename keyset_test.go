@@ -0,0 +1,145 @@
+package picosql
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	Day  string  `db:"day"`
+	Seq  int64   `db:"seq"`
+	Note *string `db:"note"`
+}
+
+func newEvents(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE events (day TEXT, seq INTEGER, note TEXT)",
+		"INSERT INTO events VALUES ('a', 1, 'x'), ('a', 2, NULL), ('b', 1, 'y'), ('b', 2, 'z'), ('c', 1, NULL)",
+	)
+	return m
+}
+
+func eventKeys(events []testEvent) []string {
+	var keys []string
+	for _, e := range events {
+		keys = append(keys, e.Day+string(rune('0'+e.Seq)))
+	}
+	return keys
+}
+
+func TestSelectKeyset(t *testing.T) {
+	m := newEvents(t)
+	opts := KeysetOptions{Keys: []string{"day", "seq"}, Limit: 2}
+
+	var pages [][]string
+	var last *KeysetPage
+	for {
+		var events []testEvent
+		page, err := m.SelectKeyset(&events, opts, "SELECT * FROM events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, eventKeys(events))
+		last = page
+		if !page.HasNext {
+			break
+		}
+		opts.Cursor = page.Next
+	}
+
+	want := [][]string{{"a1", "a2"}, {"b1", "b2"}, {"c1"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	if !last.HasPrev {
+		t.Fatal("last page has no previous page")
+	}
+
+	var events []testEvent
+	opts.Cursor = last.Prev
+	page, err := m.SelectKeyset(&events, opts, "SELECT * FROM events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventKeys(events); !reflect.DeepEqual(got, want[1]) {
+		t.Errorf("previous page = %v, want %v", got, want[1])
+	}
+	if !page.HasNext || !page.HasPrev {
+		t.Errorf("previous page flags = %+v", page)
+	}
+}
+
+func TestSelectKeysetDesc(t *testing.T) {
+	m := newEvents(t)
+	var events []testEvent
+	opts := KeysetOptions{Keys: []string{"day", "seq"}, Limit: 3, Desc: true}
+	page, err := m.SelectKeyset(&events, opts, "SELECT * FROM events WHERE day <> ?", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventKeys(events); !reflect.DeepEqual(got, []string{"b2", "b1", "a2"}) {
+		t.Errorf("rows = %v", got)
+	}
+
+	events = nil
+	opts.Cursor = page.Next
+	if _, err := m.SelectKeyset(&events, opts, "SELECT * FROM events WHERE day <> ?", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := eventKeys(events); !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("rows = %v", got)
+	}
+}
+
+func TestSelectKeysetNullKey(t *testing.T) {
+	m := newEvents(t)
+	var events []testEvent
+	opts := KeysetOptions{Keys: []string{"note"}, Limit: 1}
+	if _, err := m.SelectKeyset(&events, opts, "SELECT * FROM events WHERE note IS NULL OR note = 'x'"); err == nil {
+		t.Error("expected an error for a NULL key value")
+	}
+
+	null := base64.RawURLEncoding.EncodeToString([]byte(`{"k":[{"t":"s","v":null}]}`))
+	opts.Cursor = null
+	if _, err := m.SelectKeyset(&events, opts, "SELECT * FROM events"); err == nil {
+		t.Error("expected an error for a NULL cursor value")
+	}
+}
+
+func TestKeysetCursor(t *testing.T) {
+	for _, token := range []string{"!!", base64.RawURLEncoding.EncodeToString([]byte(`{"k":[{"t":"n","v":null}]}`))} {
+		if _, err := decodeKeysetCursor(token); err == nil {
+			t.Errorf("decodeKeysetCursor(%q) accepted an invalid cursor", token)
+		}
+	}
+
+	type row struct {
+		I int64     `db:"i"`
+		U uint64    `db:"u"`
+		F float64   `db:"f"`
+		B bool      `db:"b"`
+		T time.Time `db:"t"`
+	}
+	m := newTestDB(t)
+	at := time.Date(2024, 2, 3, 4, 5, 6, 7, time.UTC)
+	token, err := m.encodeKeysetCursor(reflect.ValueOf(row{-1, 1 << 63, 1.5, true, at}), []string{"i", "u", "f", "b", "t"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := decodeKeysetCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []interface{}
+	for _, k := range c.Keys {
+		got = append(got, k.Value)
+	}
+	want := []interface{}{int64(-1), uint64(1 << 63), 1.5, true, at}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,663 @@
+package picosql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMigrationsTable = "picosql_migrations"
+
+// Migration is one schema change, loaded from a NNNN_name.up.sql file and its
+// optional NNNN_name.down.sql counterpart.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type MigrateOptions struct {
+	// Table records the applied versions, picosql_migrations when empty.
+	Table string
+	// LockTimeout is how long to wait for another deployer to finish,
+	// one minute when zero.
+	LockTimeout time.Duration
+	// Progress is called before every migration is applied or rolled back.
+	Progress func(m *Migration, up bool)
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the migrations of dir in fsys, an embed.FS or
+// os.DirFS, sorted by version. Other files are ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Migration %s: %v", e.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mg
+		} else if mg.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d has two names: %s and %s", version, mg.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mg.Up = string(content)
+		} else {
+			mg.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if len(strings.TrimSpace(mg.Up)) == 0 {
+			return nil, fmt.Errorf("Migration %d (%s) has no up script", mg.Version, mg.Name)
+		}
+		mg.Checksum = checksum(mg.Up)
+		migrations = append(migrations, mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LoadMigrationsDir reads the migrations of a directory on disk.
+func LoadMigrationsDir(dir string) ([]*Migration, error) {
+	return LoadMigrations(os.DirFS(dir), ".")
+}
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// migrationDialect is implemented by the dialects that can run migrations.
+type migrationDialect interface {
+	// transactionalDDL reports whether schema changes can be rolled back.
+	transactionalDDL() bool
+	// splitScript cuts a script into the pieces one Exec can run.
+	splitScript(script string) []string
+	// tryLockQuery returns a query whose single value is truthy when the
+	// named lock was taken, or "" when the engine needs no lock.
+	tryLockQuery(name string) (string, []interface{})
+	unlockQuery(name string) (string, []interface{})
+}
+
+type migrator struct {
+	d     Dialect
+	md    migrationDialect
+	conn  *sql.Conn
+	table string
+	opts  MigrateOptions
+}
+
+func (m *Sql) migrator(ctx context.Context, opts MigrateOptions) (*migrator, error) {
+	d := m.Dialect()
+	md, ok := d.(migrationDialect)
+	if !ok {
+		return nil, notSupported(d, "Migrations")
+	}
+
+	m.open()
+	if !m.IsOpen {
+		return nil, connectionError
+	}
+
+	if len(opts.Table) == 0 {
+		opts.Table = defaultMigrationsTable
+	}
+	if err := ValidateIdentifier(d, opts.Table); err != nil {
+		return nil, err
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = time.Minute
+	}
+
+	// Locks are held by a session, so everything runs on one connection.
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mg := &migrator{d: d, md: md, conn: conn, table: d.QuoteIdent(opts.Table), opts: opts}
+	if err := mg.lock(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := mg.createTable(ctx); err != nil {
+		mg.close(ctx)
+		return nil, err
+	}
+	return mg, nil
+}
+
+func (mg *migrator) lock(ctx context.Context) error {
+	query, args := mg.md.tryLockQuery(mg.opts.Table)
+	if len(query) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(mg.opts.LockTimeout)
+	for {
+		var v interface{}
+		if err := mg.conn.QueryRowContext(ctx, query, args...).Scan(&v); err != nil {
+			return err
+		}
+		if asBool(v) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("Timed out waiting for the migration lock")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (mg *migrator) close(ctx context.Context) {
+	if query, args := mg.md.unlockQuery(mg.opts.Table); len(query) > 0 {
+		mg.conn.ExecContext(ctx, query, args...)
+	}
+	mg.conn.Close()
+}
+
+func (mg *migrator) createTable(ctx context.Context) error {
+	// Probed on the held connection, the pool may have no other.
+	if rows, err := mg.conn.QueryContext(ctx, "SELECT * FROM "+mg.table+" WHERE 1 = 0"); err == nil {
+		rows.Close()
+		return nil
+	}
+
+	appliedAt, _, _ := goColumnType(mg.d, timeType, nil)
+	_, err := mg.conn.ExecContext(ctx, "CREATE TABLE "+mg.table+" ("+
+		mg.d.QuoteIdent("version")+" BIGINT NOT NULL PRIMARY KEY, "+
+		mg.d.QuoteIdent("name")+" VARCHAR(255) NOT NULL, "+
+		mg.d.QuoteIdent("checksum")+" VARCHAR(64) NOT NULL, "+
+		mg.d.QuoteIdent("applied_at")+" "+appliedAt+" NOT NULL)")
+	return err
+}
+
+func (mg *migrator) applied(ctx context.Context) ([]*AppliedMigration, error) {
+	rows, err := mg.conn.QueryContext(ctx, "SELECT "+
+		mg.d.QuoteIdent("version")+", "+mg.d.QuoteIdent("name")+", "+
+		mg.d.QuoteIdent("checksum")+", "+mg.d.QuoteIdent("applied_at")+
+		" FROM "+mg.table+" ORDER BY "+mg.d.QuoteIdent("version"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []*AppliedMigration
+	for rows.Next() {
+		a := &AppliedMigration{}
+		var at interface{}
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &at); err != nil {
+			return nil, err
+		}
+
+		switch v := at.(type) {
+		case time.Time:
+			a.AppliedAt = v
+		default:
+			if t := parseAnyTime(asString(v), []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00"}); t != nil {
+				a.AppliedAt = *t
+			}
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+type execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// run executes a script and records (up) or forgets (down) its version, in
+// one transaction when the dialect can roll schema changes back. It reports
+// false when, inside an exclusive transaction, the migration turns out to be
+// run by another deployer already.
+func (mg *migrator) run(ctx context.Context, migration *Migration, up bool) (bool, error) {
+	script := migration.Up
+	record := "INSERT INTO " + mg.table + " (" +
+		mg.d.QuoteIdent("version") + ", " + mg.d.QuoteIdent("name") + ", " +
+		mg.d.QuoteIdent("checksum") + ", " + mg.d.QuoteIdent("applied_at") + ") VALUES (" +
+		mg.d.Placeholder(1) + ", " + mg.d.Placeholder(2) + ", " + mg.d.Placeholder(3) + ", " + mg.d.Placeholder(4) + ")"
+	args := []interface{}{migration.Version, migration.Name, migration.Checksum, time.Now().UTC()}
+
+	if !up {
+		script = migration.Down
+		record = "DELETE FROM " + mg.table + " WHERE " + mg.d.QuoteIdent("version") + " = " + mg.d.Placeholder(1)
+		args = args[:1]
+	}
+
+	var ex execer = mg.conn
+	transactional := mg.md.transactionalDDL()
+	commit := func() error { return nil }
+	rollback := func() {}
+
+	if eb, ok := mg.md.(interface{ exclusiveBegin() string }); ok {
+		// The engine has no session lock, so an exclusive transaction
+		// serializes deployers instead and the version is checked again
+		// once it is held.
+		if _, err := mg.conn.ExecContext(ctx, eb.exclusiveBegin()); err != nil {
+			return false, err
+		}
+		commit = func() error {
+			_, err := mg.conn.ExecContext(ctx, "COMMIT")
+			return err
+		}
+		rollback = func() { mg.conn.ExecContext(ctx, "ROLLBACK") }
+
+		var n int64
+		if err := mg.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+mg.table+" WHERE "+mg.d.QuoteIdent("version")+" = "+mg.d.Placeholder(1), migration.Version).Scan(&n); err != nil {
+			rollback()
+			return false, err
+		}
+		if (n > 0) == up {
+			rollback()
+			return false, nil
+		}
+	} else if transactional {
+		tx, err := mg.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		ex, commit, rollback = tx, tx.Commit, func() { tx.Rollback() }
+	}
+
+	fail := func(err error) (bool, error) {
+		if transactional {
+			rollback()
+			return false, fmt.Errorf("Migration %d (%s): %v", migration.Version, migration.Name, err)
+		}
+		return false, fmt.Errorf("Migration %d (%s) may be partially applied: %v", migration.Version, migration.Name, err)
+	}
+
+	for _, s := range mg.md.splitScript(script) {
+		if len(strings.TrimSpace(s)) == 0 {
+			continue
+		}
+		if _, err := ex.ExecContext(ctx, s); err != nil {
+			return fail(err)
+		}
+	}
+
+	if _, err := ex.ExecContext(ctx, record, args...); err != nil {
+		return fail(err)
+	}
+
+	if err := commit(); err != nil {
+		rollback()
+		return false, err
+	}
+	return true, nil
+}
+
+// Migrate applies, in version order, every migration that is not recorded as
+// applied yet and returns them. It refuses to run when an applied migration
+// was edited since.
+func (m *Sql) Migrate(migrations []*Migration, opts MigrateOptions) ([]*Migration, error) {
+	ctx := context.Background()
+	mg, err := m.migrator(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer mg.close(ctx)
+
+	applied, err := mg.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]bool)
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	pending := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+
+	var ran []*Migration
+	for _, migration := range pending {
+		if opts.Progress != nil {
+			opts.Progress(migration, true)
+		}
+		done, err := mg.run(ctx, migration, true)
+		if err != nil {
+			return ran, err
+		}
+		if done {
+			ran = append(ran, migration)
+		}
+	}
+	return ran, nil
+}
+
+// RollbackTo runs the down scripts of the applied migrations newer than
+// version, newest first, and returns them. Rolling back to 0 undoes them all.
+func (m *Sql) RollbackTo(migrations []*Migration, version int64, opts MigrateOptions) ([]*Migration, error) {
+	ctx := context.Background()
+	mg, err := m.migrator(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer mg.close(ctx)
+
+	applied, err := mg.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var undo []*Migration
+	for x := len(applied) - 1; x >= 0 && applied[x].Version > version; x-- {
+		migration, ok := byVersion[applied[x].Version]
+		if !ok {
+			return nil, fmt.Errorf("Migration %d (%s) is applied but was not loaded", applied[x].Version, applied[x].Name)
+		}
+		if len(strings.TrimSpace(migration.Down)) == 0 {
+			return nil, fmt.Errorf("Migration %d (%s) has no down script", migration.Version, migration.Name)
+		}
+		undo = append(undo, migration)
+	}
+
+	var ran []*Migration
+	for _, migration := range undo {
+		if opts.Progress != nil {
+			opts.Progress(migration, false)
+		}
+		done, err := mg.run(ctx, migration, false)
+		if err != nil {
+			return ran, err
+		}
+		if done {
+			ran = append(ran, migration)
+		}
+	}
+	return ran, nil
+}
+
+// AppliedMigrations returns the versions recorded in the tracking table,
+// oldest first.
+func (m *Sql) AppliedMigrations(opts MigrateOptions) ([]*AppliedMigration, error) {
+	ctx := context.Background()
+	mg, err := m.migrator(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer mg.close(ctx)
+
+	return mg.applied(ctx)
+}
+
+func verifyChecksums(migrations []*Migration, applied []*AppliedMigration) error {
+	checksums := make(map[int64]string)
+	for _, a := range applied {
+		checksums[a.Version] = a.Checksum
+	}
+
+	for _, migration := range migrations {
+		sum, ok := checksums[migration.Version]
+		if ok && sum != migration.Checksum {
+			return fmt.Errorf("Migration %d (%s) was changed after it was applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits a script on semicolons outside of quotes, comments
+// and PostgreSQL dollar-quoted bodies. A "DELIMITER xx" line changes the
+// separator like in the mysql client, for scripts that create routines.
+// Backslash escapes and # comments are only recognized for MySQL.
+func SplitStatements(d Dialect, script string) []string {
+	mysql := d.Name() == "mysql"
+	var statements []string
+	var current strings.Builder
+	delimiter := ";"
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); len(s) > 0 {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); {
+		atLineStart := i == 0 || script[i-1] == '\n'
+		if atLineStart {
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+
+			line := strings.TrimSpace(script[i : i+end])
+			if fields := strings.Fields(line); len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+				flush()
+				delimiter = fields[1]
+				i += end
+				continue
+			}
+		}
+
+		c := script[i]
+		switch {
+		case strings.HasPrefix(script[i:], delimiter):
+			flush()
+			i += len(delimiter)
+			continue
+
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(script) {
+				if script[end] == '\\' && mysql && c != '"' {
+					end += 2
+					continue
+				}
+				if script[end] == c {
+					break
+				}
+				end++
+			}
+			if end < len(script) {
+				end++
+			}
+			current.WriteString(script[i:end])
+			i = end
+			continue
+
+		case strings.HasPrefix(script[i:], "--") || (c == '#' && mysql):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end
+			continue
+
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i
+			} else {
+				end += 4
+			}
+			current.WriteString(script[i : i+end])
+			i += end
+			continue
+
+		case c == '$':
+			if tag := dollarTag.FindString(script[i:]); len(tag) > 0 {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					end = len(script) - i
+				} else {
+					end += 2 * len(tag)
+				}
+				current.WriteString(script[i : i+end])
+				i += end
+				continue
+			}
+		}
+
+		current.WriteByte(c)
+		i++
+	}
+
+	flush()
+	return statements
+}
+
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+var batchSeparator = regexp.MustCompile(`(?i)^\s*GO(\s+(\d+))?\s*$`)
+
+// SplitBatches splits a SQL Server script on GO lines, as sqlcmd does. "GO n"
+// repeats the batch n times.
+func SplitBatches(script string) []string {
+	var batches []string
+	var current []string
+
+	for _, line := range strings.Split(script, "\n") {
+		match := batchSeparator.FindStringSubmatch(strings.TrimSuffix(line, "\r"))
+		if match == nil {
+			current = append(current, line)
+			continue
+		}
+
+		count := 1
+		if len(match[2]) > 0 {
+			count, _ = strconv.Atoi(match[2])
+		}
+		if batch := strings.TrimSpace(strings.Join(current, "\n")); len(batch) > 0 {
+			for x := 0; x < count; x++ {
+				batches = append(batches, batch)
+			}
+		}
+		current = current[:0]
+	}
+
+	if batch := strings.TrimSpace(strings.Join(current, "\n")); len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// lockKey turns a lock name into the integer PostgreSQL advisory locks take.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("picosql:" + name))
+	return int64(h.Sum64())
+}
+
+// MySQL runs schema changes outside of transactions and its driver needs one
+// statement per Exec.
+
+func (mysqlDialect) transactionalDDL() bool { return false }
+
+func (d mysqlDialect) splitScript(script string) []string { return SplitStatements(d, script) }
+
+func (mysqlDialect) tryLockQuery(name string) (string, []interface{}) {
+	return "SELECT GET_LOCK(?, 0)", []interface{}{"picosql:" + name}
+}
+
+func (mysqlDialect) unlockQuery(name string) (string, []interface{}) {
+	return "SELECT RELEASE_LOCK(?)", []interface{}{"picosql:" + name}
+}
+
+func (postgresDialect) transactionalDDL() bool { return true }
+
+func (postgresDialect) splitScript(script string) []string { return []string{script} }
+
+func (postgresDialect) tryLockQuery(name string) (string, []interface{}) {
+	return "SELECT pg_try_advisory_lock($1)", []interface{}{lockKey(name)}
+}
+
+func (postgresDialect) unlockQuery(name string) (string, []interface{}) {
+	return "SELECT pg_advisory_unlock($1)", []interface{}{lockKey(name)}
+}
+
+// SQLite has no session lock; every migration runs in an exclusive
+// transaction instead.
+
+func (sqliteDialect) transactionalDDL() bool { return true }
+
+func (sqliteDialect) splitScript(script string) []string { return []string{script} }
+
+func (sqliteDialect) exclusiveBegin() string { return "BEGIN EXCLUSIVE" }
+
+func (sqliteDialect) tryLockQuery(name string) (string, []interface{}) { return "", nil }
+
+func (sqliteDialect) unlockQuery(name string) (string, []interface{}) { return "", nil }
+
+func (sqlServerDialect) transactionalDDL() bool { return true }
+
+// GO is a client command, not T-SQL, so scripts run one batch at a time.
+func (sqlServerDialect) splitScript(script string) []string { return SplitBatches(script) }
+
+func (sqlServerDialect) tryLockQuery(name string) (string, []interface{}) {
+	return "DECLARE @r int; EXEC @r = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0; SELECT CASE WHEN @r >= 0 THEN 1 ELSE 0 END",
+		[]interface{}{"picosql:" + name}
+}
+
+func (sqlServerDialect) unlockQuery(name string) (string, []interface{}) {
+	return "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", []interface{}{"picosql:" + name}
+}
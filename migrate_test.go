@@ -0,0 +1,136 @@
+package picosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/0001_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\nINSERT INTO users VALUES (1, 'a;b');")},
+	"migrations/0001_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"migrations/0002_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id INTEGER);")},
+	"migrations/0002_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+	"migrations/README.md":           {Data: []byte("ignored")},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "notes" || len(migrations[0].Down) == 0 {
+		t.Fatalf("migrations = %+v", migrations)
+	}
+
+	bad := fstest.MapFS{"m/0001_x.down.sql": {Data: []byte("DROP TABLE x")}}
+	if _, err := LoadMigrations(bad, "m"); err == nil {
+		t.Error("a migration without an up script was loaded")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	m := newTestDB(t)
+	migrations, err := LoadMigrations(testMigrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := m.Migrate(migrations, MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 2 {
+		t.Fatalf("ran %d migrations", len(ran))
+	}
+	if ran, err = m.Migrate(migrations, MigrateOptions{}); err != nil || len(ran) != 0 {
+		t.Fatalf("second run: %d migrations, %v", len(ran), err)
+	}
+
+	applied, err := m.AppliedMigrations(MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[1].Version != 2 || applied[0].AppliedAt.IsZero() {
+		t.Fatalf("applied = %+v", applied)
+	}
+
+	edited := *migrations[0]
+	edited.Checksum = checksum(edited.Up + " ")
+	if _, err := m.Migrate([]*Migration{&edited, migrations[1]}, MigrateOptions{}); err == nil {
+		t.Error("an edited migration was accepted")
+	}
+
+	if ran, err = m.RollbackTo(migrations, 1, MigrateOptions{}); err != nil || len(ran) != 1 || ran[0].Version != 2 {
+		t.Fatalf("rollback: %v, %v", ran, err)
+	}
+	if m.tableExists("notes") || !m.tableExists("users") {
+		t.Error("rollback left the wrong tables")
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	m := newTestDB(t)
+	broken := []*Migration{{Version: 1, Name: "broken", Up: "CREATE TABLE a (id INTEGER); INSERT INTO missing VALUES (1);"}}
+	broken[0].Checksum = checksum(broken[0].Up)
+
+	if _, err := m.Migrate(broken, MigrateOptions{}); err == nil {
+		t.Fatal("a broken migration succeeded")
+	}
+	if m.tableExists("a") {
+		t.Error("a failed migration was not rolled back")
+	}
+	if applied, _ := m.AppliedMigrations(MigrateOptions{}); len(applied) != 0 {
+		t.Errorf("applied = %+v", applied)
+	}
+}
+
+func TestMigrateSkipsAppliedUnderLock(t *testing.T) {
+	m := newTestDB(t)
+	ctx := context.Background()
+	mg, err := m.migrator(ctx, MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mg.close(ctx)
+
+	migration := &Migration{Version: 7, Name: "x", Up: "CREATE TABLE x (id INTEGER)"}
+	if _, err := mg.conn.ExecContext(ctx, "INSERT INTO picosql_migrations VALUES (7, 'x', '', '2024-01-01 00:00:00')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another deployer recorded the version after the pending list was read.
+	done, err := mg.run(ctx, migration, true)
+	if err != nil || done {
+		t.Fatalf("run = %v, %v", done, err)
+	}
+	if done, err = mg.run(ctx, migration, false); err != nil || !done {
+		t.Fatalf("down = %v, %v", done, err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	for _, tc := range []struct {
+		d      Dialect
+		script string
+		want   []string
+	}{
+		{MySQLDialect, "SELECT 1 # a; b\n; SELECT 'x\\';y'", []string{"SELECT 1 # a; b", "SELECT 'x\\';y'"}},
+		{PostgresDialect, "SELECT 1 # 2; SELECT 'x\\'; SELECT 3", []string{"SELECT 1 # 2", "SELECT 'x\\'", "SELECT 3"}},
+		{PostgresDialect, "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT 'it''s; fine' -- c;\n", []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT 'it''s; fine' -- c;"}},
+		{MySQLDialect, "DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; END//\nDELIMITER ;\nSELECT 2;", []string{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "SELECT 2"}},
+	} {
+		if got := SplitStatements(tc.d, tc.script); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %q:\n got %q\nwant %q", tc.d.Name(), tc.script, got, tc.want)
+		}
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	script := "CREATE TABLE a (id int);\r\nGO\r\nINSERT INTO a VALUES (1); SELECT 'GO'\ngo 2\n  Go  \nSELECT 1"
+	want := []string{"CREATE TABLE a (id int);", "INSERT INTO a VALUES (1); SELECT 'GO'", "INSERT INTO a VALUES (1); SELECT 'GO'", "SELECT 1"}
+	if got := SplitBatches(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
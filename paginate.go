@@ -0,0 +1,54 @@
+package picosql
+
+import (
+	"errors"
+	"strings"
+)
+
+type Page struct {
+	Page    int
+	PerPage int
+	Total   int64
+	Pages   int
+	HasNext bool
+	HasPrev bool
+}
+
+// Paginate selects one page of query into targets and counts the rows of the
+// whole query, wrapped as a derived table, in the same call. Pages start at 1.
+func (m *Sql) Paginate(targets interface{}, page, perPage int, query string, args ...interface{}) (*Page, error) {
+	if perPage <= 0 {
+		return nil, errors.New("Per page must be greater than zero")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	// a trailing ";" would end the derived table and the LIMIT clause early
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+
+	total, err := m.Count("SELECT COUNT(*) FROM ("+query+") AS picosql_count", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Page{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+		Pages:   int((total + int64(perPage) - 1) / int64(perPage)),
+		HasPrev: page > 1,
+	}
+	p.HasNext = page < p.Pages
+
+	offset := (page - 1) * perPage
+	if int64(offset) >= total {
+		return p, nil
+	}
+
+	if err := m.Select(targets, m.Dialect().LimitOffset(query, perPage, offset), args...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,35 @@
+package picosql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	m := newEvents(t)
+
+	var events []testEvent
+	p, err := m.Paginate(&events, 2, 2, "SELECT * FROM events WHERE seq >= ? ORDER BY day, seq;\n", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Page{Page: 2, PerPage: 2, Total: 5, Pages: 3, HasNext: true, HasPrev: true}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("page = %+v, want %+v", p, want)
+	}
+	if got := eventKeys(events); !reflect.DeepEqual(got, []string{"b1", "b2"}) {
+		t.Errorf("rows = %v", got)
+	}
+
+	events = nil
+	if p, err = m.Paginate(&events, 9, 2, "SELECT * FROM events"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 || p.HasNext || !p.HasPrev || p.Pages != 3 {
+		t.Errorf("page past the end = %+v, %d rows", p, len(events))
+	}
+
+	if _, err := m.Paginate(&events, 1, 0, "SELECT * FROM events"); err == nil {
+		t.Error("expected an error for perPage 0")
+	}
+}
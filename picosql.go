@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,13 +25,47 @@ type ColumnDefinition struct {
 	OrdinalPosition string          `db:"ordinal_position"`
 	DataType        string          `db:"data_type"`
 	ColumnType      string          `db:"column_type"`
-	SqlColumnType   *sql.ColumnType `db:"-"`
+	IsNullable      bool            `db:"is_nullable"`
+	Default         *string         `db:"column_default"`
+	AutoIncrement   bool            `db:"auto_increment"`
+	CharacterSet    string          `db:"character_set"`
+	Collation       string          `db:"collation"`
+	Comment         string          `db:"column_comment"`
+	SqlColumnType   *sql.ColumnType `db:"-" json:"-"`
 }
 
 type TableStructure struct {
 	TableName    string
 	DatabaseName string
 	Columns      []*ColumnDefinition
+	PrimaryKey   []string
+	Indexes      []*IndexDefinition
+	ForeignKeys  []*ForeignKeyDefinition
+}
+
+type IndexColumn struct {
+	Name   string
+	Desc   bool
+	Length int // prefix length, 0 for the whole column
+}
+
+type IndexDefinition struct {
+	Name    string
+	Unique  bool
+	Primary bool
+	Columns []IndexColumn
+	// Include lists non-key columns stored in the index, making it covering
+	// on PostgreSQL and SQL Server. Only PostgreSQL introspects it.
+	Include []string
+}
+
+type ForeignKeyDefinition struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
 }
 
 type Sql struct {
@@ -41,6 +74,7 @@ type Sql struct {
 	db      *sql.DB
 	cs      string
 	driver  string
+	dialect Dialect
 	tm      tagMapper
 	isClone bool
 }
@@ -150,10 +184,13 @@ func (m *Sql) IsEmptyResultError(err error) bool {
 }
 
 func (m *Sql) GetTableInfo(tn string) (*TableInfo, error) {
-	//show table status where name = 'Business'
-	q := fmt.Sprintf(`show table status where name = '%s'`, tn)
+	if err := m.validateIdentifiers(tn); err != nil {
+		return nil, err
+	}
+
+	q, args := m.Dialect().TableInfoQuery(tn)
 	var single TableInfo
-	err := m.Get(&single, q)
+	err := m.Get(&single, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +217,11 @@ func (m *Sql) Count(query string, args ...interface{}) (int64, error) {
 }
 
 func (m *Sql) RCount(t string) (int64, error) {
-	q := `SELECT COUNT(*) FROM ` + t
+	table, err := m.quoteName(t)
+	if err != nil {
+		return 0, err
+	}
+	q := `SELECT COUNT(*) FROM ` + table
 
 	m.open()
 
@@ -191,7 +232,7 @@ func (m *Sql) RCount(t string) (int64, error) {
 	res := m.db.QueryRow(q)
 
 	var count int64
-	err := res.Scan(&count)
+	err = res.Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -532,69 +573,26 @@ func (m *Sql) Select(targets interface{}, query string, args ...interface{}) err
 	defer res.Close()
 
 	sliceValue := reflect.ValueOf(targets).Elem()
-	itemType := sliceValue.Type()
-	elementType := itemType.Elem()
+	elementType := sliceValue.Type().Elem()
 
-	if elementType.Kind() == reflect.Ptr {
-		elementType = elementType.Elem()
+	columns, err := res.Columns()
+	if err != nil {
+		return err
 	}
 
-	isPrimitive := elementType.Kind() != reflect.Struct
-
-	var tm map[string]string
-	if !isPrimitive {
-		tm = m.tm.get(elementType)
-	}
-	//fmt.Println(tm)
 	for res.Next() {
-		columns, err := res.Columns()
+		values, err := scanValues(res, len(columns))
 		if err != nil {
 			return err
 		}
 
-		result := make([]interface{}, len(columns))
-
-		for x := 0; x < len(result); x++ {
-			result[x] = new(interface{})
-		}
-
-		err = res.Scan(result...)
-
-		if err != nil {
-			return err
-		}
-
-		if isPrimitive {
-			target := reflect.New(elementType)
-			setValue(target.Elem(), *(result[0].(*interface{})))
-			sliceValue.Set(reflect.Append(sliceValue, target.Elem()))
-			continue
-		}
-
 		target := reflect.New(elementType)
-		v := target.Elem()
-
-		for i, c := range columns {
-			cv := *(result[i].(*interface{}))
-			var field reflect.Value
-			fn, ok := tm[c]
-			if !ok {
-				continue
-			}
-			field = v.FieldByName(fn)
-
-			if !field.IsValid() {
-				continue
-			}
-
-			err := setValue(field, cv)
-			if err != nil {
-				fmt.Println(err)
-			}
+		if err := fillTarget(m.tm, target.Interface(), columns, values); err != nil {
+			return err
 		}
-		sliceValue.Set(reflect.Append(sliceValue, target))
+		sliceValue.Set(reflect.Append(sliceValue, target.Elem()))
 	}
-	return nil
+	return res.Err()
 }
 
 func (m *Sql) Get(target interface{}, query string, args ...interface{}) error {
@@ -863,10 +861,9 @@ func (m *Sql) Maps(query string, args ...interface{}) ([]map[string]interface{},
 }
 
 func (m *Sql) ListTables(dbName string) ([]string, error) {
-	//sql := `SELECT TABLE_NAME from information_schema WHERE TABLE_SCHEMA = '` + dbName + "'"
-	sql := `SHOW tables;`
+	sql, args := m.Dialect().ListTablesQuery(dbName)
 	var tables []string
-	res, err := m.Query(sql)
+	res, err := m.Query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -917,7 +914,14 @@ func (m *Sql) simpleQuery(query string) error {
 }
 
 func (m *Sql) CreateDatabase(dbName string) error {
-	q := "CREATE DATABASE `" + dbName + "`  DEFAULT CHARACTER SET latin1"
+	if err := m.validateIdentifiers(dbName); err != nil {
+		return err
+	}
+
+	q := m.Dialect().CreateDatabaseSQL(dbName)
+	if len(q) == 0 {
+		return notSupported(m.Dialect(), "CREATE DATABASE")
+	}
 	res, err := m.Exec(q)
 	if err != nil {
 		return err
@@ -929,7 +933,11 @@ func (m *Sql) CreateDatabase(dbName string) error {
 }
 
 func (m *Sql) UserExists(userName string) bool {
-	res, err := m.Query("SELECT User from mysql.user WHERE User = ? LIMIT 1", userName)
+	q, args := m.Dialect().UserExistsQuery(userName)
+	if len(q) == 0 {
+		return false
+	}
+	res, err := m.Query(q, args...)
 	if err != nil {
 		fmt.Println(err)
 		return false
@@ -944,29 +952,16 @@ func (m *Sql) UserExists(userName string) bool {
 }
 
 func (m *Sql) createUser(userName, password string) error {
-	if m.UserExists(userName) {
-		return nil
-	}
-	sql := "CREATE USER '" + userName + "'@'%' IDENTIFIED BY '" + password + "'"
-	_, err := m.Exec(sql)
-	if err != nil {
-		return err
-	}
-	return nil
+	return m.CreateUser(userName, "", password)
 }
 
 func (m *Sql) AssignPermissions(db, userName string) error {
-	sql := fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO '%s'@'%' WITH GRANT OPTION;", db, userName)
-	_, err := m.Exec(sql)
-	if err != nil {
-		return err
-	}
-	return nil
+	return m.Grant(userName, "", GrantOptions{Privileges: []string{"ALL PRIVILEGES"}, Database: db, GrantOption: true})
 }
 
 func (m *Sql) DatabaseExists(db string) (bool, error) {
-	sql := "show databases"
-	res, err := m.Query(sql)
+	sql, args := m.Dialect().ListDatabasesQuery()
+	res, err := m.Query(sql, args...)
 	if err != nil {
 		return false, err
 	}
@@ -982,9 +977,20 @@ func (m *Sql) DatabaseExists(db string) (bool, error) {
 	return false, nil
 }
 
+// GetCurrentStructure returns the columns of a table. Dialects with schema
+// introspection fill every ColumnDefinition field; IntrospectTable adds the
+// keys, indexes and foreign keys.
 func (m *Sql) GetCurrentStructure(dbName, tableName string) (*TableStructure, error) {
-	sql := fmt.Sprintf(`select column_name,ordinal_position,data_type,column_type from information_schema.COLUMNS where table_schema = '%s' and table_name = '%s'`, dbName, tableName)
-	mps, err := m.Maps(sql)
+	if si, ok := m.Dialect().(schemaIntrospector); ok {
+		columns, err := si.introspectColumns(m, dbName, tableName)
+		if err != nil {
+			return nil, err
+		}
+		return &TableStructure{TableName: tableName, DatabaseName: dbName, Columns: columns}, nil
+	}
+
+	sql, args := m.Dialect().ColumnsQuery(dbName, tableName)
+	mps, err := m.Maps(sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1013,86 +1019,42 @@ func (m *Sql) GetCurrentStructure(dbName, tableName string) (*TableStructure, er
 }
 
 func (m *Sql) DropTable(tableName string) error {
-	sql := `DROP TABLE ` + tableName
-	_, err := m.Exec(sql)
+	table, err := m.quoteName(tableName)
+	if err != nil {
+		return err
+	}
+	sql := `DROP TABLE ` + table
+	_, err = m.Exec(sql)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Sql) columnDefinitionStringBasedOnType(c string, t *ColumnTypeSimplified) string {
-	l := t.Length
-	dbType := t.DBType
-
-	if l == 0 {
-		l = 100
-	}
-
-	if l > 1024 {
-		//l = 1024
-		dbType = "TEXT"
-	}
-
-	if strings.Contains(c, "url") && l < 255 {
-		l = 500
-	}
-
-	p := 20
-	s := 5
-
-	// if t.Precison == 0 || t.Scale == 0 {
-	// 	p = 20
-	// 	s = 5
-	// }
-
-	switch dbType {
-	case "DATETIME":
-		fallthrough
-	case "DATE":
-		return "`" + c + "` DATE DEFAULT NULL,"
-	// case "DATETIME":
-	// 	return "`" + c + "` DATETIME DEFAULT NULL,"
-	case "INT":
-		return "`" + c + "` INT(11) DEFAULT NULL,"
-	case "BIGINT":
-		return "`" + c + "` BIGINT(20) DEFAULT NULL,"
-	case "NTEXT":
-		fallthrough
-	case "VARCHAR":
-		fallthrough
-	case "CHAR":
-		fallthrough
-	case "NVARCHAR":
-		return "`" + c + "` VARCHAR(" + strconv.Itoa(int(l)) + ") DEFAULT NULL,"
-	case "TEXT":
-		return "`" + c + "` TEXT DEFAULT NULL,"
-	case "BIT":
-		return "`" + c + "` BIT DEFAULT NULL,"
-	case "MONEY":
-		fallthrough
-	case "DECIMAL":
-		return "`" + c + "` DECIMAL(" + strconv.Itoa(int(p)) + "," + strconv.Itoa(int(s)) + ") DEFAULT NULL,"
-	}
-	return ""
+func (m *Sql) columnDefinitionStringBasedOnType(c string, t *ColumnTypeSimplified) (string, error) {
+	return m.Dialect().ColumnDefinition(c, t)
 }
 
 func (m *Sql) DropTableNew(db, tableName string) error {
-	sql := fmt.Sprintf("Drop Table `%s`.`%s`", db, tableName)
+	if err := m.validateIdentifiers(db, tableName); err != nil {
+		return err
+	}
+	sql := "Drop Table " + quoteQualified(m.Dialect(), db, tableName)
 	_, err := m.Exec(sql)
 	return err
 }
 
+// CreateUniqueIndex creates a unique index named basic on the comma separated
+// columns of keyField. CreateIndex takes a name, directions and prefixes.
 func (m *Sql) CreateUniqueIndex(db, tableName, keyField string) error {
-	var sb string
-	sb += fmt.Sprintf("ALTER TABLE `%s`.`%s` ", db, tableName)
-	sb += "ADD UNIQUE INDEX `basic` ("
 	var keys = strings.Split(keyField, ",")
-	for i := 0; i < len(keys); i++ {
-		sb += fmt.Sprintf("`%s` ASC,", keys[i])
+	for i, k := range keys {
+		keys[i] = strings.TrimSpace(k)
 	}
-	sb = sb[0 : len(sb)-1]
-	sb += ");"
+	if err := m.validateIdentifiers(append([]string{db, tableName}, keys...)...); err != nil {
+		return err
+	}
+	sb := m.Dialect().CreateUniqueIndexSQL(db, tableName, "basic", keys)
 	_, err := m.Exec(sb)
 	if err != nil {
 		return err
@@ -1101,28 +1063,37 @@ func (m *Sql) CreateUniqueIndex(db, tableName, keyField string) error {
 }
 
 func (m *Sql) CreateTable(tableName string, columns []string, types []*ColumnTypeSimplified, keyField string) error {
-	//fields := strings.Split(header, ",")
-	sql := " CREATE TABLE `" + tableName + "` ("
+	table, err := m.quoteName(tableName)
+	if err != nil {
+		return err
+	}
+	if _, err := m.quoteColumns(columns); err != nil {
+		return err
+	}
+	d := m.Dialect()
+	var defs []string
 	for i, f := range columns {
-		cd := m.columnDefinitionStringBasedOnType(f, types[i])
+		t := types[i]
 		if f == keyField {
-			cd = strings.Replace(cd, "DEFAULT NULL", "NOT NULL", -1)
+			key := *t
+			key.IsNullable = false
+			t = &key
 		}
-		sql += cd
-	}
 
-	sql += "`current_hash` VARCHAR(25) DEFAULT NULL,"
-	if len(keyField) > 0 {
-		if strings.Contains(keyField, ",") {
-			sql = sql[0 : len(sql)-1]
-		} else {
-			sql += "PRIMARY KEY (`" + keyField + "`)"
+		cd, err := m.columnDefinitionStringBasedOnType(f, t)
+		if err != nil {
+			return err
 		}
-	} else {
-		sql = sql[0 : len(sql)-1]
+		defs = append(defs, cd)
 	}
-	sql += ` ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
-	_, err := m.Exec(sql)
+
+	defs = append(defs, d.QuoteIdent("current_hash")+" VARCHAR(25) NULL")
+	if len(keyField) > 0 && !strings.Contains(keyField, ",") {
+		defs = append(defs, "PRIMARY KEY ("+d.QuoteIdent(keyField)+")")
+	}
+
+	sql := " CREATE TABLE " + table + " (" + strings.Join(defs, ",") + ")" + d.TableOptions() + ";"
+	_, err = m.Exec(sql)
 	if err != nil {
 		return err
 	}
@@ -1130,17 +1101,25 @@ func (m *Sql) CreateTable(tableName string, columns []string, types []*ColumnTyp
 }
 
 func (m *Sql) CreateTableNoHashOrKey(tableName string, columns []string, types []*ColumnTypeSimplified) error {
-
-	//fields := strings.Split(header, ",")
-	sql := " CREATE TABLE `" + tableName + "` ("
+	table, err := m.quoteName(tableName)
+	if err != nil {
+		return err
+	}
+	if _, err := m.quoteColumns(columns); err != nil {
+		return err
+	}
+	d := m.Dialect()
+	var defs []string
 	for i, f := range columns {
-		cd := m.columnDefinitionStringBasedOnType(f, types[i])
-		sql += cd
+		cd, err := m.columnDefinitionStringBasedOnType(f, types[i])
+		if err != nil {
+			return err
+		}
+		defs = append(defs, cd)
 	}
 
-	sql = sql[0 : len(sql)-1]
-	sql += ` ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
-	_, err := m.Exec(sql)
+	sql := " CREATE TABLE " + table + " (" + strings.Join(defs, ",") + ")" + d.TableOptions() + ";"
+	_, err = m.Exec(sql)
 	if err != nil {
 		return err
 	}
@@ -1160,6 +1139,7 @@ func (m *Sql) Close() {
 		m.cs = ""
 		m.db = nil
 		m.driver = ""
+		m.dialect = nil
 		m.tm = nil
 		return
 	}
@@ -1175,6 +1155,8 @@ func (m *Sql) Clone() (*Sql, error) {
 		IsOpen:  m.IsOpen,
 		cs:      m.cs,
 		db:      m.db,
+		driver:  m.driver,
+		dialect: m.dialect,
 		retries: m.retries,
 		tm:      m.tm,
 		isClone: true,
@@ -1192,7 +1174,7 @@ func (m *Sql) Clone() (*Sql, error) {
 }
 
 func New(driver, cs string) (*Sql, error) {
-	s := &Sql{cs: cs, driver: driver}
+	s := &Sql{cs: cs, driver: driver, dialect: DialectFor(driver)}
 	return s, s.open()
 }
 
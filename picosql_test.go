@@ -0,0 +1,29 @@
+package picosql
+
+import (
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestDB opens an in-memory SQLite database. A single connection keeps
+// every statement on the same database, and blocks when rows are left open.
+func newTestDB(t *testing.T) *Sql {
+	t.Helper()
+	m, err := New("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetMaxOpenConns(1)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func mustExec(t *testing.T, m *Sql, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := m.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
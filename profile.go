@@ -0,0 +1,304 @@
+package picosql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+type ProfileOptions struct {
+	// Database qualifies the table, the current one when empty.
+	Database string
+	// Columns to profile, all of them when empty.
+	Columns []string
+	// SampleRows limits the profile to about SampleRows rows, 0 for the whole
+	// table. PostgreSQL draws them at random with TABLESAMPLE BERNOULLI, sized
+	// from its row estimate; other engines take the first rows they return,
+	// which is fast but need not be representative.
+	SampleRows int
+	// ApproxDistinct takes distinct counts from the statistics of the engine
+	// where it keeps them: pg_stats on PostgreSQL, index cardinality on
+	// MySQL. Other columns are counted exactly, and estimated columns are not
+	// counted at all.
+	ApproxDistinct bool
+	// TopN is the number of most frequent values reported per column, 5 by
+	// default and none when negative.
+	TopN int
+}
+
+type ValueCount struct {
+	Value interface{}
+	Count int64
+}
+
+type ColumnProfile struct {
+	Column         string
+	DataType       string
+	Nulls          int64
+	Distinct       int64
+	DistinctApprox bool `json:",omitempty"`
+	// Min and Max are nil for types that can not be ordered, such as
+	// booleans, JSON and binary columns.
+	Min interface{} `json:",omitempty"`
+	Max interface{} `json:",omitempty"`
+	// AvgLength is the average length in characters of text columns.
+	AvgLength float64      `json:",omitempty"`
+	Top       []ValueCount `json:",omitempty"`
+}
+
+type TableProfile struct {
+	Table string
+	// Rows is the number of rows profiled, those of the sample when Sampled.
+	Rows    int64
+	Sampled bool
+	Columns []*ColumnProfile
+}
+
+// distinctEstimator is implemented by the dialects that keep distinct value
+// estimates per column.
+type distinctEstimator interface {
+	distinctEstimates(m *Sql, db, table string) (map[string]int64, error)
+}
+
+// tableSampler is implemented by the dialects that can draw a random sample of
+// rows.
+type tableSampler interface {
+	// sampleSQL selects about rows random rows of table, the same ones each
+	// time it runs, and reports whether it leaves rows out.
+	sampleSQL(m *Sql, db, table string, rows int) (string, bool, error)
+}
+
+// lengthFunction is the character length function of each engine, LENGTH
+// when missing.
+var lengthFunction = map[string]string{
+	"mysql":     "CHAR_LENGTH",
+	"sqlserver": "LEN",
+}
+
+// ProfileTable reports, per column of table, the null and distinct counts,
+// min and max, average length of text and most frequent values.
+func (m *Sql) ProfileTable(table string, opts ProfileOptions) (*TableProfile, error) {
+	d := m.Dialect()
+	if err := m.validateIdentifiers(append([]string{opts.Database, table}, opts.Columns...)...); err != nil {
+		return nil, err
+	}
+
+	var ts *TableStructure
+	var err error
+	if _, ok := d.(schemaIntrospector); ok {
+		ts, err = m.IntrospectTable(opts.Database, table)
+	} else {
+		ts, err = m.GetCurrentStructure(opts.Database, table)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := profiledColumns(ts, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TopN == 0 {
+		opts.TopN = 5
+	}
+
+	source := quoteQualified(d, opts.Database, table)
+	sampled := false
+	if opts.SampleRows > 0 {
+		sample := d.LimitOffset("SELECT * FROM "+source, opts.SampleRows, 0)
+		if sp, ok := d.(tableSampler); ok {
+			if sample, sampled, err = sp.sampleSQL(m, opts.Database, table, opts.SampleRows); err != nil {
+				return nil, err
+			}
+		}
+		source = "(" + sample + ") profiled"
+	}
+
+	p := &TableProfile{Table: table}
+	if p.Rows, err = m.Count("SELECT COUNT(*) FROM " + source); err != nil {
+		return nil, err
+	}
+	p.Sampled = opts.SampleRows > 0 && (sampled || p.Rows == int64(opts.SampleRows))
+
+	var estimates map[string]int64
+	if de, ok := d.(distinctEstimator); ok && opts.ApproxDistinct {
+		if estimates, err = de.distinctEstimates(m, opts.Database, table); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range columns {
+		cp, err := m.profileColumn(source, c, p.Rows, estimates, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.Columns = append(p.Columns, cp)
+	}
+	return p, nil
+}
+
+func profiledColumns(ts *TableStructure, names []string) ([]*ColumnDefinition, error) {
+	if len(names) == 0 {
+		return ts.Columns, nil
+	}
+
+	columns := make([]*ColumnDefinition, len(names))
+	for i, n := range names {
+		for _, c := range ts.Columns {
+			if strings.EqualFold(c.ColumnName, n) {
+				columns[i] = c
+				break
+			}
+		}
+		if columns[i] == nil {
+			return nil, fmt.Errorf("Column %s does not exist in table %s", n, ts.TableName)
+		}
+	}
+	return columns, nil
+}
+
+// profileKind tells whether values of dataType are text, and whether they can
+// be ordered and compared for MIN, MAX and DISTINCT.
+func profileKind(dataType string) (text, ordered, comparable bool) {
+	t := strings.ToLower(dataType)
+	switch {
+	case strings.Contains(t, "json"), strings.Contains(t, "xml"):
+		return false, false, t == "jsonb"
+	case strings.Contains(t, "blob"), strings.Contains(t, "binary"), t == "bytea", t == "image":
+		return false, false, true
+	case strings.Contains(t, "bool"), t == "bit", t == "uuid", t == "uniqueidentifier":
+		return false, false, true
+	case strings.Contains(t, "char"), strings.Contains(t, "text"), strings.Contains(t, "clob"), t == "enum", t == "set":
+		return true, true, true
+	}
+	return false, true, true
+}
+
+func (m *Sql) profileColumn(source string, c *ColumnDefinition, rows int64, estimates map[string]int64, opts ProfileOptions) (*ColumnProfile, error) {
+	d := m.Dialect()
+	col := d.QuoteIdent(c.ColumnName)
+	text, ordered, comparable := profileKind(c.DataType)
+
+	estimate, approx := estimates[c.ColumnName]
+
+	selects := []string{"COUNT(" + col + ") AS non_null"}
+	if comparable && !approx {
+		selects = append(selects, "COUNT(DISTINCT "+col+") AS distinct_count")
+	}
+	if ordered {
+		selects = append(selects, "MIN("+col+") AS min_value", "MAX("+col+") AS max_value")
+	}
+	if text {
+		length := lengthFunction[d.Name()]
+		if len(length) == 0 {
+			length = "LENGTH"
+		}
+		selects = append(selects, "AVG("+length+"("+col+")) AS avg_length")
+	}
+
+	r, err := m.Map("SELECT " + strings.Join(selects, ", ") + " FROM " + source)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &ColumnProfile{
+		Column:    c.ColumnName,
+		DataType:  c.DataType,
+		Nulls:     rows - asInt(r["non_null"]),
+		Distinct:  asInt(r["distinct_count"]),
+		Min:       r["min_value"],
+		Max:       r["max_value"],
+		AvgLength: asFloat(r["avg_length"]),
+	}
+
+	if approx {
+		cp.Distinct = estimate
+		cp.DistinctApprox = true
+	}
+
+	if !comparable || opts.TopN < 0 {
+		return cp, nil
+	}
+
+	top, err := m.Maps(d.LimitOffset("SELECT "+col+" AS value, COUNT(*) AS value_count FROM "+source+
+		" WHERE "+col+" IS NOT NULL GROUP BY "+col+" ORDER BY value_count DESC", opts.TopN, 0))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range top {
+		cp.Top = append(cp.Top, ValueCount{Value: t["value"], Count: asInt(t["value_count"])})
+	}
+	return cp, nil
+}
+
+// WriteJSON writes the profile as indented JSON.
+func (p *TableProfile) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(p)
+}
+
+func (mysqlDialect) distinctEstimates(m *Sql, db, table string) (map[string]int64, error) {
+	rows, err := m.Maps(`SELECT COLUMN_NAME AS column_name, MAX(CARDINALITY) AS distinct_count
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND SEQ_IN_INDEX = 1
+		GROUP BY COLUMN_NAME`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return distinctCounts(rows), nil
+}
+
+// A negative n_distinct is the share of rows, for columns whose distinct
+// values grow with the table.
+func (postgresDialect) distinctEstimates(m *Sql, db, table string) (map[string]int64, error) {
+	rows, err := m.Maps(`SELECT s.attname AS column_name,
+		CASE WHEN s.n_distinct < 0 THEN -s.n_distinct * GREATEST(c.reltuples, 0) ELSE s.n_distinct END AS distinct_count
+		FROM pg_stats s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.tablename
+		WHERE s.schemaname = COALESCE(NULLIF($1, ''), current_schema()) AND s.tablename = $2`, db, table)
+	if err != nil {
+		return nil, err
+	}
+	return distinctCounts(rows), nil
+}
+
+// sampleSQL oversamples by a fifth, as BERNOULLI returns a varying number of
+// rows. Without an estimate, on tables never analyzed, every row qualifies and
+// the sample is the first rows. The seed keeps the sample the same across the
+// queries of one profile.
+func (d postgresDialect) sampleSQL(m *Sql, db, table string, rows int) (string, bool, error) {
+	var estimate float64
+	err := m.QueryRow(`SELECT GREATEST(c.reltuples, 0) FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relname = $2`, db, table).Scan(&estimate)
+	if err != nil {
+		return "", false, err
+	}
+
+	percent := 100.0
+	if estimate > 0 {
+		percent = math.Min(100, float64(rows)/estimate*120)
+	}
+
+	q := fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI (%s) REPEATABLE (%d)",
+		quoteQualified(d, db, table), strconv.FormatFloat(percent, 'f', -1, 64), rand.Int31())
+	return d.LimitOffset(q, rows, 0), percent < 100, nil
+}
+
+func distinctCounts(rows []map[string]interface{}) map[string]int64 {
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		if r["distinct_count"] == nil {
+			continue
+		}
+		counts[asString(r["column_name"])] = asInt(r["distinct_count"])
+	}
+	return counts
+}
@@ -0,0 +1,116 @@
+package picosql
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testProfileDialect keeps a distinct estimate for name and samples the even
+// ids, like the dialects that sample and estimate.
+type testProfileDialect struct{ sqliteDialect }
+
+func (testProfileDialect) distinctEstimates(m *Sql, db, table string) (map[string]int64, error) {
+	return map[string]int64{"name": 42}, nil
+}
+
+func (testProfileDialect) sampleSQL(m *Sql, db, table string, rows int) (string, bool, error) {
+	return "SELECT * FROM " + table + " WHERE id % 2 = 0", true, nil
+}
+
+func newProfiled(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, age INTEGER, photo BLOB)",
+		"INSERT INTO people VALUES (1, 'ann', 30, NULL), (2, 'bob', 40, x'01'), (3, 'ann', NULL, NULL), (4, 'cy', 30, NULL)",
+	)
+	return m
+}
+
+func TestProfileTable(t *testing.T) {
+	m := newProfiled(t)
+	p, err := m.ProfileTable("people", ProfileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 4 || p.Sampled || len(p.Columns) != 4 {
+		t.Fatalf("profile = %+v", p)
+	}
+
+	name := p.Columns[1]
+	if name.Distinct != 3 || name.DistinctApprox || name.Min != "ann" || name.Max != "cy" || name.AvgLength != 2.75 {
+		t.Errorf("name = %+v", name)
+	}
+	if want := []ValueCount{{"ann", 2}}; !reflect.DeepEqual(name.Top[:1], want) {
+		t.Errorf("name top = %v", name.Top)
+	}
+
+	age := p.Columns[2]
+	if age.Nulls != 1 || age.Distinct != 2 || age.Min != int64(30) || age.Max != int64(40) {
+		t.Errorf("age = %+v", age)
+	}
+
+	photo := p.Columns[3]
+	if photo.Nulls != 3 || photo.Min != nil || photo.Max != nil {
+		t.Errorf("photo = %+v", photo)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded TableProfile
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Rows != 4 {
+		t.Errorf("JSON = %s, %v", buf.String(), err)
+	}
+}
+
+func TestProfileTableOptions(t *testing.T) {
+	m := newProfiled(t)
+	p, err := m.ProfileTable("people", ProfileOptions{Columns: []string{"AGE"}, SampleRows: 2, TopN: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 2 || !p.Sampled || len(p.Columns) != 1 || p.Columns[0].Top != nil {
+		t.Errorf("prefix sample = %+v %+v", p, p.Columns[0])
+	}
+
+	m.SetDialect(testProfileDialect{})
+	p, err = m.ProfileTable("people", ProfileOptions{Columns: []string{"id", "name"}, SampleRows: 3, ApproxDistinct: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 2 || !p.Sampled {
+		t.Errorf("random sample = %+v", p)
+	}
+	if id := p.Columns[0]; id.Distinct != 2 || id.DistinctApprox || id.Min != int64(2) {
+		t.Errorf("id = %+v", id)
+	}
+	if name := p.Columns[1]; name.Distinct != 42 || !name.DistinctApprox {
+		t.Errorf("name = %+v", name)
+	}
+
+	if _, err := m.ProfileTable("people", ProfileOptions{Columns: []string{"missing"}}); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+}
+
+func TestProfileKind(t *testing.T) {
+	for _, tt := range []struct {
+		dataType                  string
+		text, ordered, comparable bool
+	}{
+		{"VARCHAR(20)", true, true, true},
+		{"integer", false, true, true},
+		{"jsonb", false, false, true},
+		{"json", false, false, false},
+		{"bytea", false, false, true},
+		{"boolean", false, false, true},
+	} {
+		text, ordered, comparable := profileKind(tt.dataType)
+		if text != tt.text || ordered != tt.ordered || comparable != tt.comparable {
+			t.Errorf("profileKind(%q) = %v %v %v", tt.dataType, text, ordered, comparable)
+		}
+	}
+}
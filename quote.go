@@ -0,0 +1,81 @@
+package picosql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxIdentLength is the longest identifier each engine accepts.
+var maxIdentLength = map[string]int{
+	"mysql":     64,
+	"postgres":  63,
+	"sqlserver": 128,
+}
+
+// ValidateIdentifier rejects names that are not safe as a database, table,
+// column or user name in dialect d, even once quoted. Letters, digits, _, $
+// and -, plus spaces between them, are allowed.
+func ValidateIdentifier(d Dialect, name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("Empty identifier")
+	}
+
+	if max := maxIdentLength[d.Name()]; max > 0 && utf8.RuneCountInString(name) > max {
+		return fmt.Errorf("Identifier %q is longer than %d characters", name, max)
+	}
+
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("Identifier %q starts or ends with a space", name)
+	}
+
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '-' || r == ' ' {
+			continue
+		}
+		return fmt.Errorf("Identifier %q contains the illegal character %q", name, r)
+	}
+	return nil
+}
+
+// quoteName validates and quotes a table name that may be qualified with its
+// database as db.table.
+func (m *Sql) quoteName(name string) (string, error) {
+	d := m.Dialect()
+	parts := strings.Split(name, ".")
+	for _, p := range parts {
+		if err := ValidateIdentifier(d, p); err != nil {
+			return "", err
+		}
+	}
+	return quoteQualified(d, parts...), nil
+}
+
+// validateIdentifiers validates every name, skipping empty optional ones.
+func (m *Sql) validateIdentifiers(names ...string) error {
+	d := m.Dialect()
+	for _, n := range names {
+		if len(n) == 0 {
+			continue
+		}
+		if err := ValidateIdentifier(d, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteColumns validates and quotes column names, which unlike
+// validateIdentifiers must not be empty.
+func (m *Sql) quoteColumns(names []string) ([]string, error) {
+	d := m.Dialect()
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		if err := ValidateIdentifier(d, n); err != nil {
+			return nil, err
+		}
+		quoted[i] = d.QuoteIdent(n)
+	}
+	return quoted, nil
+}
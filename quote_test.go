@@ -0,0 +1,79 @@
+package picosql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIdentifier(t *testing.T) {
+	for _, name := range []string{"users", "user_accounts", "Ünïcode", "order-items", "my table", "$tmp"} {
+		if err := ValidateIdentifier(MySQLDialect, name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", " users", "a.b", "a`b", `a"b`, "a]b", "a;b", "a'b", "a\x00b", strings.Repeat("x", 65)} {
+		if err := ValidateIdentifier(MySQLDialect, name); err == nil {
+			t.Errorf("%q was accepted", name)
+		}
+	}
+
+	long := strings.Repeat("x", 100)
+	if ValidateIdentifier(SQLServerDialect, long) != nil || ValidateIdentifier(PostgresDialect, long) == nil {
+		t.Error("identifier length limits are not per dialect")
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	tests := []struct {
+		d              Dialect
+		ident, literal string
+	}{
+		{MySQLDialect, "`a``b`.`c`", `'it''s \\ \n'`},
+		{PostgresDialect, `"a""b"."c"`, `E'it''s \\ ` + "\n'"},
+		{SQLiteDialect, `"a""b"."c"`, `'it''s \ ` + "\n'"},
+		{SQLServerDialect, `[a"b].[c]`, `N'it''s \ ` + "\n'"},
+	}
+	for _, tt := range tests {
+		ident := `a"b`
+		if tt.d == MySQLDialect {
+			ident = "a`b"
+		}
+		if got := quoteQualified(tt.d, "", ident, "c"); got != tt.ident {
+			t.Errorf("%s: quoteQualified = %s, want %s", tt.d.Name(), got, tt.ident)
+		}
+		if got := tt.d.QuoteLiteral("it's \\ \n"); got != tt.literal {
+			t.Errorf("%s: QuoteLiteral = %s, want %s", tt.d.Name(), got, tt.literal)
+		}
+	}
+}
+
+func TestQualifiedTableNames(t *testing.T) {
+	m := newTestDB(t)
+	types := []*ColumnTypeSimplified{{DBType: "INT"}, {DBType: "TEXT", IsNullable: true}}
+
+	if err := m.CreateTable("main.keyed", []string{"id", "name"}, types, "id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateTableNoHashOrKey("main.plain", []string{"id", "name"}, types); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := m.RCount("main.keyed"); err != nil || n != 0 {
+		t.Fatalf("RCount = %d, %v", n, err)
+	}
+
+	for _, bad := range []string{"keyed; DROP TABLE plain", "a.b.c.", "x`y"} {
+		if err := m.CreateTableNoHashOrKey(bad, []string{"id"}, types[:1]); err == nil {
+			t.Errorf("table %q was created", bad)
+		}
+		if err := m.DropTable(bad); err == nil {
+			t.Errorf("table %q was dropped", bad)
+		}
+	}
+	if err := m.CreateTable("other", []string{"id", "x) ; --"}, types, "id"); err == nil {
+		t.Error("an invalid column name was accepted")
+	}
+	if !m.tableExists("plain") {
+		t.Error("plain is gone")
+	}
+}
@@ -1,65 +1,279 @@
 package picosql
 
 import (
+	"database/sql"
 	"fmt"
 	"reflect"
 	"time"
 
 	"errors"
 	"strconv"
+	"strings"
 )
 
+// setValue stores a value returned by the driver in field, converting it to
+// the kind of the field. NULL leaves the zero value. Pointer fields get a new
+// value, and fields implementing sql.Scanner, such as sql.NullString, scan it
+// themselves.
 func setValue(field reflect.Value, v interface{}) error {
+	if field.CanAddr() {
+		if s, ok := field.Addr().Interface().(sql.Scanner); ok {
+			return s.Scan(v)
+		}
+	}
+
+	if v == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(field.Type().Elem())
+		if err := setValue(p.Elem(), v); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+
+	switch nv := v.(type) {
+	case []byte:
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes(append([]byte(nil), nv...))
+			return nil
+		}
+		v = string(nv)
+	case time.Time:
+		if field.Kind() == reflect.String {
+			field.SetString(nv.Format(time.RFC3339Nano))
+			return nil
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(fmt.Sprint(v))
+	case reflect.Bool:
+		b, err := boolValue(v)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := intValue(v)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("Value %d overflows %s", n, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := uintValue(v)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(n) {
+			return fmt.Errorf("Value %d overflows %s", n, field.Type())
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := floatValue(v)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		if s, ok := v.(string); ok && field.Type() == reflect.TypeOf(time.Time{}) {
+			t, err := parseTime(s)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+
+		rv := reflect.ValueOf(v)
+		if !rv.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("Can not store %T in a field of type %s", v, field.Type())
+		}
+		field.Set(rv.Convert(field.Type()))
+	}
+	return nil
+}
+
+func boolValue(v interface{}) (bool, error) {
+	switch nv := v.(type) {
+	case bool:
+		return nv, nil
+	case int64:
+		return nv != 0, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(nv))
+	}
+	return false, fmt.Errorf("Can not store %T in a bool", v)
+}
+
+func intValue(v interface{}) (int64, error) {
 	switch nv := v.(type) {
+	case int64:
+		return nv, nil
+	case float64:
+		return int64(nv), nil
+	case bool:
+		if nv {
+			return 1, nil
+		}
+		return 0, nil
 	case string:
-		field.SetString(nv)
-	case int:
-		if field.Kind() == reflect.Bool {
-			field.SetBool(nv == 1)
-		} else {
-			field.SetInt(int64(nv))
+		s := strings.TrimSpace(nv)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
 		}
+		f, err := strconv.ParseFloat(s, 64)
+		return int64(f), err
+	}
+	return 0, fmt.Errorf("Can not store %T in an integer", v)
+}
+
+func uintValue(v interface{}) (uint64, error) {
+	switch nv := v.(type) {
 	case int64:
-		if field.Kind() == reflect.Bool {
-			field.SetBool(nv == 1)
-		} else {
-			field.SetInt(nv)
+		if nv < 0 {
+			return 0, fmt.Errorf("Value %d is negative", nv)
 		}
+		return uint64(nv), nil
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(nv), 10, 64)
+	}
+	n, err := intValue(v)
+	if err == nil && n < 0 {
+		return 0, fmt.Errorf("Value %d is negative", n)
+	}
+	return uint64(n), err
+}
+
+func floatValue(v interface{}) (float64, error) {
+	switch nv := v.(type) {
 	case float64:
-		field.SetFloat(nv)
-	case time.Time:
-		if field.Kind() == reflect.Ptr {
-			field.Set(reflect.ValueOf(&nv))
-			break
+		return nv, nil
+	case int64:
+		return float64(nv), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(nv), 64)
+	}
+	return 0, fmt.Errorf("Can not store %T in a float", v)
+}
+
+// timeLayouts are the text forms of times returned by drivers that do not
+// parse them, such as MySQL without parseTime.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
 		}
-		field.Set(reflect.ValueOf(nv))
-	case bool:
-		field.SetBool(nv)
-	case []byte:
+	}
+	return time.Time{}, fmt.Errorf("Can not parse time %q", s)
+}
 
-		if field.Kind() == reflect.String {
-			field.SetString(string(nv))
-		} else if field.Kind() == reflect.Slice {
-			field.SetBytes(nv)
-		} else if field.Kind() == reflect.Int {
-			str := string(nv)
-			i, _ := strconv.Atoi(str)
-			field.SetInt(int64(i))
-		} else if field.Kind() == reflect.Bool {
-			str := string(nv)
-			field.SetBool(str == "1")
-		} else if field.Kind() == reflect.Int64 {
-			str := string(nv)
-			i, _ := strconv.Atoi(str)
-			field.SetInt(int64(i))
-		} else if field.Kind() == reflect.Float64 {
-			str := string(nv)
-			i, _ := strconv.ParseFloat(str, 64)
-			field.SetFloat(i)
-		} else {
-			fmt.Println(field.Kind(), nv)
-			return errors.New("Invalid Field type for bytes:")
+func scanValues(rows *sql.Rows, n int) ([]interface{}, error) {
+	result := make([]interface{}, n)
+	for x := 0; x < n; x++ {
+		result[x] = new(interface{})
+	}
+
+	if err := rows.Scan(result...); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, n)
+	for x := 0; x < n; x++ {
+		values[x] = *(result[x].(*interface{}))
+	}
+	return values, nil
+}
+
+func fillStruct(v reflect.Value, tm map[string]string, columns []string, values []interface{}) error {
+	var first error
+	for i, c := range columns {
+		fn, ok := tm[c]
+		if !ok {
+			continue
+		}
+
+		field := v.FieldByName(fn)
+		if !field.IsValid() {
+			continue
+		}
+
+		if err := setValue(field, values[i]); err != nil && first == nil {
+			first = err
 		}
 	}
-	return nil
+	return first
+}
+
+func fillMap(mp map[string]interface{}, columns []string, values []interface{}) {
+	for i, c := range columns {
+		mp[c] = values[i]
+		switch v := values[i].(type) {
+		case []uint8:
+			mp[c] = string(v)
+		}
+	}
+}
+
+func fillTarget(tm tagMapper, target interface{}, columns []string, values []interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Unable to scan into %T: %v", target, r)
+		}
+	}()
+
+	if mp, ok := target.(map[string]interface{}); ok {
+		if mp == nil {
+			return errors.New("Scan target map is nil")
+		}
+		fillMap(mp, columns, values)
+		return nil
+	}
+
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("Scan target must be a non-nil pointer")
+	}
+	v = v.Elem()
+
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		_, isTime := v.Interface().(time.Time)
+		_, isScanner := v.Addr().Interface().(sql.Scanner)
+		if !isTime && !isScanner {
+			return fillStruct(v, tm.get(v.Type()), columns, values)
+		}
+	case reflect.Map:
+		mp, ok := v.Addr().Interface().(*map[string]interface{})
+		if !ok {
+			return errors.New("Scan target map must be map[string]interface{}")
+		}
+		if *mp == nil {
+			*mp = make(map[string]interface{})
+		}
+		fillMap(*mp, columns, values)
+		return nil
+	}
+
+	if len(values) == 0 {
+		return errors.New("No columns in result set")
+	}
+	return setValue(v, values[0])
 }
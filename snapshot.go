@@ -0,0 +1,278 @@
+package picosql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type SchemaSnapshot struct {
+	Database string
+	Dialect  string
+	Tables   []*TableStructure
+}
+
+const (
+	SchemaAdded   = "added"
+	SchemaRemoved = "removed"
+	SchemaChanged = "changed"
+)
+
+type SchemaChange struct {
+	Action string // added, removed or changed
+	Object string // table, column, index or foreign_key
+	Table  string
+	Name   string
+	From   string `json:",omitempty"`
+	To     string `json:",omitempty"`
+}
+
+type SchemaDiff struct {
+	Changes []*SchemaChange
+}
+
+// Snapshot introspects every table of db.
+func (m *Sql) Snapshot(db string) (*SchemaSnapshot, error) {
+	tables, err := m.ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchemaSnapshot{Database: db, Dialect: m.Dialect().Name()}
+	for _, t := range tables {
+		ts, err := m.IntrospectTable(db, t)
+		if err != nil {
+			return nil, err
+		}
+		s.Tables = append(s.Tables, ts)
+	}
+
+	s.normalize()
+	return s, nil
+}
+
+// normalize orders tables, indexes and foreign keys by name so that two
+// snapshots of the same schema serialize identically. Columns keep their
+// ordinal order.
+func (s *SchemaSnapshot) normalize() {
+	sort.Slice(s.Tables, func(i, j int) bool { return s.Tables[i].TableName < s.Tables[j].TableName })
+	for _, t := range s.Tables {
+		sort.SliceStable(t.Columns, func(i, j int) bool {
+			a, _ := strconv.Atoi(t.Columns[i].OrdinalPosition)
+			b, _ := strconv.Atoi(t.Columns[j].OrdinalPosition)
+			return a < b
+		})
+		sort.Slice(t.Indexes, func(i, j int) bool { return t.Indexes[i].Name < t.Indexes[j].Name })
+		sort.Slice(t.ForeignKeys, func(i, j int) bool { return t.ForeignKeys[i].Name < t.ForeignKeys[j].Name })
+	}
+}
+
+func (s *SchemaSnapshot) Table(name string) *TableStructure {
+	for _, t := range s.Tables {
+		if t.TableName == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the snapshot as indented JSON, identical for identical schemas.
+func (s *SchemaSnapshot) WriteJSON(w io.Writer) error {
+	s.normalize()
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(s)
+}
+
+func ReadSnapshot(r io.Reader) (*SchemaSnapshot, error) {
+	var s SchemaSnapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	s.normalize()
+	return &s, nil
+}
+
+// DiffSnapshot compares a snapshot, taken as the expected state, with the live
+// schema of the snapshot's database.
+func (m *Sql) DiffSnapshot(expected *SchemaSnapshot) (*SchemaDiff, error) {
+	live, err := m.Snapshot(expected.Database)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchemas(expected, live), nil
+}
+
+// DiffSchemas lists what changed going from one snapshot to the other.
+func DiffSchemas(from, to *SchemaSnapshot) *SchemaDiff {
+	d := &SchemaDiff{}
+
+	for _, ft := range from.Tables {
+		tt := to.Table(ft.TableName)
+		if tt == nil {
+			d.add(SchemaRemoved, "table", ft.TableName, ft.TableName, "", "")
+			continue
+		}
+		d.diffTables(ft, tt)
+	}
+
+	for _, tt := range to.Tables {
+		if from.Table(tt.TableName) == nil {
+			d.add(SchemaAdded, "table", tt.TableName, tt.TableName, "", "")
+		}
+	}
+	return d
+}
+
+func (d *SchemaDiff) diffTables(from, to *TableStructure) {
+	table := from.TableName
+
+	fromColumns := make(map[string]string)
+	for _, c := range from.Columns {
+		fromColumns[c.ColumnName] = columnSignature(c)
+	}
+	toColumns := make(map[string]string)
+	for _, c := range to.Columns {
+		toColumns[c.ColumnName] = columnSignature(c)
+	}
+	for _, c := range from.Columns {
+		d.compare("column", table, c.ColumnName, fromColumns, toColumns)
+	}
+	for _, c := range to.Columns {
+		if _, ok := fromColumns[c.ColumnName]; !ok {
+			d.add(SchemaAdded, "column", table, c.ColumnName, "", toColumns[c.ColumnName])
+		}
+	}
+
+	fromIndexes := make(map[string]string)
+	for _, idx := range from.Indexes {
+		fromIndexes[idx.Name] = indexSignature(idx)
+	}
+	toIndexes := make(map[string]string)
+	for _, idx := range to.Indexes {
+		toIndexes[idx.Name] = indexSignature(idx)
+	}
+	for _, idx := range from.Indexes {
+		d.compare("index", table, idx.Name, fromIndexes, toIndexes)
+	}
+	for _, idx := range to.Indexes {
+		if _, ok := fromIndexes[idx.Name]; !ok {
+			d.add(SchemaAdded, "index", table, idx.Name, "", toIndexes[idx.Name])
+		}
+	}
+
+	fromKeys := make(map[string]string)
+	for _, fk := range from.ForeignKeys {
+		fromKeys[fk.Name] = foreignKeySignature(fk)
+	}
+	toKeys := make(map[string]string)
+	for _, fk := range to.ForeignKeys {
+		toKeys[fk.Name] = foreignKeySignature(fk)
+	}
+	for _, fk := range from.ForeignKeys {
+		d.compare("foreign_key", table, fk.Name, fromKeys, toKeys)
+	}
+	for _, fk := range to.ForeignKeys {
+		if _, ok := fromKeys[fk.Name]; !ok {
+			d.add(SchemaAdded, "foreign_key", table, fk.Name, "", toKeys[fk.Name])
+		}
+	}
+}
+
+func (d *SchemaDiff) compare(object, table, name string, from, to map[string]string) {
+	t, ok := to[name]
+	if !ok {
+		d.add(SchemaRemoved, object, table, name, from[name], "")
+		return
+	}
+	if from[name] != t {
+		d.add(SchemaChanged, object, table, name, from[name], t)
+	}
+}
+
+func (d *SchemaDiff) add(action, object, table, name, from, to string) {
+	d.Changes = append(d.Changes, &SchemaChange{Action: action, Object: object, Table: table, Name: name, From: from, To: to})
+}
+
+func (d *SchemaDiff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+func (d *SchemaDiff) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(d)
+}
+
+// String lists one change per line, e.g. "changed column users.name: varchar(50) -> varchar(100)".
+func (d *SchemaDiff) String() string {
+	var sb strings.Builder
+	for _, c := range d.Changes {
+		name := c.Table
+		if c.Object != "table" {
+			name += "." + c.Name
+		}
+		fmt.Fprintf(&sb, "%s %s %s", c.Action, c.Object, name)
+		switch c.Action {
+		case SchemaChanged:
+			fmt.Fprintf(&sb, ": %s -> %s", c.From, c.To)
+		case SchemaAdded:
+			if len(c.To) > 0 {
+				fmt.Fprintf(&sb, ": %s", c.To)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func columnSignature(c *ColumnDefinition) string {
+	s := strings.ToLower(c.ColumnType)
+	if c.IsNullable {
+		s += " NULL"
+	} else {
+		s += " NOT NULL"
+	}
+	if c.Default != nil {
+		s += " DEFAULT " + *c.Default
+	}
+	if c.AutoIncrement {
+		s += " AUTO_INCREMENT"
+	}
+	if len(c.Collation) > 0 {
+		s += " COLLATE " + c.Collation
+	}
+	if len(c.Comment) > 0 {
+		s += " COMMENT " + strconv.Quote(c.Comment)
+	}
+	return s
+}
+
+func indexSignature(idx *IndexDefinition) string {
+	columns := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		columns[i] = c.Name
+		if c.Length > 0 {
+			columns[i] += "(" + strconv.Itoa(c.Length) + ")"
+		}
+		if c.Desc {
+			columns[i] += " DESC"
+		}
+	}
+
+	s := "(" + strings.Join(columns, ", ") + ")"
+	switch {
+	case idx.Primary:
+		s = "PRIMARY KEY " + s
+	case idx.Unique:
+		s = "UNIQUE " + s
+	}
+	return s
+}
+
+func foreignKeySignature(fk *ForeignKeyDefinition) string {
+	return "(" + strings.Join(fk.Columns, ", ") + ") REFERENCES " + fk.RefTable + " (" + strings.Join(fk.RefColumns, ", ") + ") ON UPDATE " + fk.OnUpdate + " ON DELETE " + fk.OnDelete
+}
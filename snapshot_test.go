@@ -0,0 +1,65 @@
+package picosql
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	m := newSchema(t)
+	s, err := m.Snapshot("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tables) != 2 || s.Tables[0].TableName != "customers" || s.Dialect != "sqlite" {
+		t.Fatalf("snapshot = %+v", s)
+	}
+
+	var a, b bytes.Buffer
+	if err := s.WriteJSON(&a); err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.Snapshot("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := again.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != b.String() {
+		t.Fatalf("snapshots differ:\n%s\n%s", a.String(), b.String())
+	}
+
+	read, err := ReadSnapshot(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := DiffSchemas(read, s); d.HasChanges() {
+		t.Fatalf("read snapshot differs:\n%s", d)
+	}
+}
+
+func TestDiffSnapshot(t *testing.T) {
+	m := newSchema(t)
+	expected, err := m.Snapshot("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustExec(t, m,
+		"ALTER TABLE customers ADD COLUMN age INTEGER",
+		"DROP INDEX customers_email",
+		"CREATE TABLE notes (id INTEGER)",
+	)
+
+	d, err := m.DiffSnapshot(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "added column customers.age: integer NULL\n" +
+		"removed index customers.customers_email\n" +
+		"added table notes\n"
+	if d.String() != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", d, want)
+	}
+}
@@ -0,0 +1,295 @@
+package picosql
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TableStats struct {
+	Table string
+	// Rows is counted when RowsExact is set, estimated by the engine
+	// otherwise.
+	Rows      int64
+	RowsExact bool
+	// DataBytes, IndexBytes and FreeBytes are the space used by rows and
+	// indexes and the space allocated but unused. FreeBytes is estimated from
+	// dead rows on PostgreSQL. They are -1 when unknown, on SQLite built
+	// without dbstat.
+	DataBytes  int64
+	IndexBytes int64
+	FreeBytes  int64
+	// AutoIncrementColumn is the auto increment, serial or identity column,
+	// empty when there is none. AutoIncrementUsed is the percentage of its
+	// maximum value used so far.
+	AutoIncrementColumn string
+	AutoIncrement       int64
+	AutoIncrementMax    float64
+	AutoIncrementUsed   float64
+	// UpdateTime is the last change of the table, nil where the engine does
+	// not record it.
+	UpdateTime *time.Time
+}
+
+type StatsOptions struct {
+	// Fast only reports the estimates of the engine, never running COUNT(*).
+	Fast bool
+	// ExactLimit is the largest estimate whose rows are counted, 1000000 by
+	// default. Tables SQLite has no estimate for are always counted.
+	ExactLimit int64
+}
+
+// statsDialect is implemented by the dialects that can report table sizes.
+type statsDialect interface {
+	// tableStats returns the tables of db with estimated rows and sizes.
+	tableStats(m *Sql, db string) ([]*TableStats, error)
+	// autoIncrementStats fills the auto increment columns of stats.
+	autoIncrementStats(m *Sql, db string, stats map[string]*TableStats) error
+}
+
+// DatabaseStats reports the estimated rows, sizes, auto increment headroom and
+// last update of every table of db, the current database or schema when
+// empty. MySQL 8 caches these figures for information_schema_stats_expiry
+// seconds.
+func (m *Sql) DatabaseStats(db string, opts StatsOptions) ([]*TableStats, error) {
+	sd, ok := m.Dialect().(statsDialect)
+	if !ok {
+		return nil, notSupported(m.Dialect(), "Table statistics")
+	}
+
+	if err := m.validateIdentifiers(db); err != nil {
+		return nil, err
+	}
+
+	if opts.ExactLimit == 0 {
+		opts.ExactLimit = 1000000
+	}
+
+	stats, err := sd.tableStats(m, db)
+	if err != nil {
+		return nil, err
+	}
+
+	byTable := make(map[string]*TableStats, len(stats))
+	for _, s := range stats {
+		byTable[s.Table] = s
+	}
+
+	if err := sd.autoIncrementStats(m, db, byTable); err != nil {
+		return nil, err
+	}
+
+	for _, s := range stats {
+		if s.AutoIncrementMax > 0 {
+			s.AutoIncrementUsed = float64(s.AutoIncrement) / s.AutoIncrementMax * 100
+		}
+
+		if opts.Fast || s.Rows > opts.ExactLimit {
+			continue
+		}
+
+		table := s.Table
+		if len(db) > 0 {
+			table = db + "." + table
+		}
+
+		if s.Rows, err = m.RCount(table); err != nil {
+			return nil, err
+		}
+		s.RowsExact = true
+	}
+	return stats, nil
+}
+
+func scanTableStats(rows []map[string]interface{}) []*TableStats {
+	stats := make([]*TableStats, len(rows))
+	for i, r := range rows {
+		stats[i] = &TableStats{
+			Table:      asString(r["table_name"]),
+			Rows:       asInt(r["estimated_rows"]),
+			DataBytes:  asInt(r["data_bytes"]),
+			IndexBytes: asInt(r["index_bytes"]),
+			FreeBytes:  asInt(r["free_bytes"]),
+			UpdateTime: asTime(r["update_time"]),
+		}
+	}
+	return stats
+}
+
+func scanAutoIncrements(rows []map[string]interface{}, stats map[string]*TableStats) {
+	for _, r := range rows {
+		s, ok := stats[asString(r["table_name"])]
+		if !ok {
+			continue
+		}
+		s.AutoIncrementColumn = asString(r["column_name"])
+		s.AutoIncrement = asInt(r["last_value"])
+		s.AutoIncrementMax = asFloat(r["max_value"])
+	}
+}
+
+func asFloat(v interface{}) float64 {
+	switch nv := v.(type) {
+	case float64:
+		return nv
+	case float32:
+		return float64(nv)
+	case int64:
+		return float64(nv)
+	case uint64:
+		return float64(nv)
+	}
+	f, _ := strconv.ParseFloat(strings.TrimSpace(asString(v)), 64)
+	return f
+}
+
+func asTime(v interface{}) *time.Time {
+	switch nv := v.(type) {
+	case time.Time:
+		if nv.IsZero() {
+			return nil
+		}
+		return &nv
+	case string, []byte:
+		t, err := time.Parse("2006-01-02 15:04:05", asString(nv))
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	return nil
+}
+
+// MySQL
+
+const mysqlIntMax = `CASE WHEN c.COLUMN_TYPE LIKE '%unsigned%' THEN
+		CASE c.DATA_TYPE WHEN 'tinyint' THEN 255 WHEN 'smallint' THEN 65535 WHEN 'mediumint' THEN 16777215
+		WHEN 'int' THEN 4294967295 ELSE 18446744073709551615 END
+	ELSE
+		CASE c.DATA_TYPE WHEN 'tinyint' THEN 127 WHEN 'smallint' THEN 32767 WHEN 'mediumint' THEN 8388607
+		WHEN 'int' THEN 2147483647 ELSE 9223372036854775807 END
+	END`
+
+func (mysqlDialect) tableStats(m *Sql, db string) ([]*TableStats, error) {
+	rows, err := m.Maps(`SELECT TABLE_NAME AS table_name, COALESCE(TABLE_ROWS, 0) AS estimated_rows,
+		COALESCE(DATA_LENGTH, 0) AS data_bytes, COALESCE(INDEX_LENGTH, 0) AS index_bytes,
+		COALESCE(DATA_FREE, 0) AS free_bytes, UPDATE_TIME AS update_time
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`, db)
+	if err != nil {
+		return nil, err
+	}
+	return scanTableStats(rows), nil
+}
+
+func (mysqlDialect) autoIncrementStats(m *Sql, db string, stats map[string]*TableStats) error {
+	rows, err := m.Maps(`SELECT t.TABLE_NAME AS table_name, c.COLUMN_NAME AS column_name,
+		COALESCE(t.AUTO_INCREMENT, 1) - 1 AS last_value, `+mysqlIntMax+` AS max_value
+		FROM information_schema.TABLES t
+		JOIN information_schema.COLUMNS c ON c.TABLE_SCHEMA = t.TABLE_SCHEMA AND c.TABLE_NAME = t.TABLE_NAME
+		WHERE t.TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND c.EXTRA LIKE '%auto_increment%'`, db)
+	if err != nil {
+		return err
+	}
+	scanAutoIncrements(rows, stats)
+	return nil
+}
+
+// PostgreSQL keeps no modification time, and reclaims dead rows with VACUUM.
+
+func (postgresDialect) tableStats(m *Sql, db string) ([]*TableStats, error) {
+	rows, err := m.Maps(`SELECT c.relname AS table_name, GREATEST(c.reltuples, 0)::bigint AS estimated_rows,
+		pg_table_size(c.oid) AS data_bytes, pg_indexes_size(c.oid) AS index_bytes,
+		COALESCE((pg_table_size(c.oid) * s.n_dead_tup / NULLIF(s.n_live_tup + s.n_dead_tup, 0))::bigint, 0) AS free_bytes
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = COALESCE(NULLIF($1, ''), current_schema())
+		ORDER BY c.relname`, db)
+	if err != nil {
+		return nil, err
+	}
+	return scanTableStats(rows), nil
+}
+
+func (postgresDialect) autoIncrementStats(m *Sql, db string, stats map[string]*TableStats) error {
+	rows, err := m.Maps(`SELECT c.relname AS table_name, a.attname AS column_name,
+		COALESCE(s.last_value, 0) AS last_value, s.max_value AS max_value
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		JOIN pg_sequences s ON format('%I.%I', s.schemaname, s.sequencename) = pg_get_serial_sequence(format('%I.%I', n.nspname, c.relname), a.attname)
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = COALESCE(NULLIF($1, ''), current_schema())`, db)
+	if err != nil {
+		return err
+	}
+	scanAutoIncrements(rows, stats)
+	return nil
+}
+
+// SQLite keeps no row estimates or modification times. Sizes come from the
+// dbstat table, when SQLite was built with it.
+
+func (d sqliteDialect) tableStats(m *Sql, db string) ([]*TableStats, error) {
+	schema := sqliteSchema(db)
+	objects, err := m.Maps(`SELECT type, name, tbl_name FROM ` + d.QuoteIdent(schema) + `.sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []*TableStats
+	byTable := make(map[string]*TableStats)
+	for _, o := range objects {
+		if asString(o["type"]) == "table" {
+			s := &TableStats{Table: asString(o["name"])}
+			stats = append(stats, s)
+			byTable[s.Table] = s
+		}
+	}
+
+	sizes, err := m.Maps(`SELECT name, SUM(pgsize) AS size, SUM(unused) AS unused FROM dbstat WHERE schema = ? GROUP BY name`, schema)
+	if err != nil {
+		for _, s := range stats {
+			s.DataBytes, s.IndexBytes, s.FreeBytes = -1, -1, -1
+		}
+		return stats, nil
+	}
+
+	owner := make(map[string]string)
+	for _, o := range objects {
+		if asString(o["type"]) == "index" {
+			owner[asString(o["name"])] = asString(o["tbl_name"])
+		}
+	}
+
+	for _, r := range sizes {
+		name := asString(r["name"])
+		if s, ok := byTable[name]; ok {
+			s.DataBytes += asInt(r["size"])
+			s.FreeBytes += asInt(r["unused"])
+		} else if s, ok := byTable[owner[name]]; ok {
+			s.IndexBytes += asInt(r["size"])
+			s.FreeBytes += asInt(r["unused"])
+		}
+	}
+	return stats, nil
+}
+
+func (d sqliteDialect) autoIncrementStats(m *Sql, db string, stats map[string]*TableStats) error {
+	schema := d.QuoteIdent(sqliteSchema(db))
+	var n int64
+	if err := m.QueryRow(`SELECT COUNT(*) FROM ` + schema + `.sqlite_master WHERE name = 'sqlite_sequence'`).Scan(&n); err != nil || n == 0 {
+		return err
+	}
+
+	rows, err := m.Maps(`SELECT s.name AS table_name, p.name AS column_name, s.seq AS last_value,
+		9223372036854775807 AS max_value
+		FROM `+schema+`.sqlite_sequence s JOIN pragma_table_info(s.name, ?) p ON p.pk = 1`, sqliteSchema(db))
+	if err != nil {
+		return err
+	}
+	scanAutoIncrements(rows, stats)
+	return nil
+}
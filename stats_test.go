@@ -0,0 +1,75 @@
+package picosql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabaseStats(t *testing.T) {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)",
+		"CREATE INDEX ix_items_name ON items (name)",
+		"INSERT INTO items (name) VALUES ('a'), ('b'), ('c')",
+		"CREATE TABLE notes (body TEXT)",
+	)
+
+	stats, err := m.DatabaseStats("", StatsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].Table != "items" || stats[1].Table != "notes" {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	items := stats[0]
+	if items.Rows != 3 || !items.RowsExact {
+		t.Errorf("rows = %d, exact %v", items.Rows, items.RowsExact)
+	}
+	if items.AutoIncrementColumn != "id" || items.AutoIncrement != 3 || items.AutoIncrementUsed <= 0 {
+		t.Errorf("auto increment = %s %d %g%%", items.AutoIncrementColumn, items.AutoIncrement, items.AutoIncrementUsed)
+	}
+	if stats[1].AutoIncrementColumn != "" {
+		t.Errorf("notes auto increment column = %q", stats[1].AutoIncrementColumn)
+	}
+
+	// Sizes depend on SQLite being built with dbstat.
+	if items.DataBytes == -1 {
+		if items.IndexBytes != -1 || items.FreeBytes != -1 {
+			t.Errorf("sizes = %d %d %d, want all unknown", items.DataBytes, items.IndexBytes, items.FreeBytes)
+		}
+	} else if items.DataBytes <= 0 || items.IndexBytes <= 0 {
+		t.Errorf("sizes = %d %d", items.DataBytes, items.IndexBytes)
+	}
+
+	fast, err := m.DatabaseStats("", StatsOptions{Fast: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fast[0].RowsExact || fast[0].Rows != 0 {
+		t.Errorf("fast rows = %d, exact %v", fast[0].Rows, fast[0].RowsExact)
+	}
+
+	if _, err := m.DatabaseStats("main;", StatsOptions{}); err == nil {
+		t.Error("expected an error for an invalid database name")
+	}
+}
+
+func TestStatsValues(t *testing.T) {
+	for _, tt := range []struct {
+		v    interface{}
+		want float64
+	}{{float32(1.5), 1.5}, {int64(3), 3}, {uint64(1 << 63), 1 << 63}, {[]byte(" 2.5 "), 2.5}, {nil, 0}} {
+		if got := asFloat(tt.v); got != tt.want {
+			t.Errorf("asFloat(%v) = %g, want %g", tt.v, got, tt.want)
+		}
+	}
+
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if got := asTime([]byte("2024-05-06 07:08:09")); got == nil || !got.Equal(want) {
+		t.Errorf("asTime = %v", got)
+	}
+	if asTime(time.Time{}) != nil || asTime("soon") != nil || asTime(int64(1)) != nil {
+		t.Error("asTime accepted a zero or invalid time")
+	}
+}
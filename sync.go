@@ -0,0 +1,336 @@
+package picosql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHashColumn = "current_hash"
+
+type SyncOptions struct {
+	// KeyColumns identify a row in both the source query and the destination.
+	// A key repeated by the source stops the sync with an error.
+	KeyColumns []string
+	// HashColumns are hashed to detect changes, all source columns when empty.
+	HashColumns []string
+	// HashColumn is the destination column holding the row hash,
+	// current_hash when empty as created by CreateTable. A source column of
+	// the same name is ignored.
+	HashColumn string
+	// Delete removes destination rows whose key is missing from the source.
+	Delete bool
+	// BatchSize is the number of rows per INSERT statement, 500 when zero and
+	// fewer when the placeholders of the engine run out.
+	BatchSize int
+	// DryRun only counts the changes that would be made.
+	DryRun bool
+}
+
+type SyncSummary struct {
+	Inserted  int64
+	Updated   int64
+	Deleted   int64
+	Unchanged int64
+}
+
+type syncTarget struct {
+	key  []interface{}
+	hash string
+}
+
+// RowHash returns a stable hash of values that fits the current_hash column.
+func RowHash(values []interface{}) string {
+	h := sha256.New()
+	for i, v := range values {
+		if i > 0 {
+			h.Write([]byte{0x1f})
+		}
+		h.Write([]byte(hashText(v)))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:24]
+}
+
+func hashText(v interface{}) string {
+	switch nv := v.(type) {
+	case nil:
+		return "\x00"
+	case []byte:
+		return string(nv)
+	case string:
+		return nv
+	case time.Time:
+		return nv.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(nv, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(nv), 'g', -1, 32)
+	case bool:
+		if nv {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+func syncKey(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = hashText(v)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// SyncTable brings dstTable in line with srcQuery by comparing a hash of every
+// source row with the hash column stored in the destination, issuing inserts
+// and updates only for new or changed rows and, optionally, deletes.
+func SyncTable(src *Sql, srcQuery string, dst *Sql, dstTable string, opts SyncOptions) (*SyncSummary, error) {
+	if len(opts.KeyColumns) == 0 {
+		return nil, errors.New("Sync requires at least one key column")
+	}
+
+	if len(opts.HashColumn) == 0 {
+		opts.HashColumn = defaultHashColumn
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	if _, err := dst.quoteName(dstTable); err != nil {
+		return nil, err
+	}
+	if _, err := dst.quoteColumns(append([]string{opts.HashColumn}, opts.KeyColumns...)); err != nil {
+		return nil, err
+	}
+
+	existing, err := dst.loadSyncTargets(dstTable, opts.KeyColumns, opts.HashColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := src.Iterate(srcQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	// The hash column of a source table synced before, e.g. one made by
+	// CreateTable, is replaced by the fresh hash rather than copied.
+	var columns []string
+	var sourceIndexes []int
+	for i, c := range it.Columns() {
+		if !strings.EqualFold(c, opts.HashColumn) {
+			columns = append(columns, c)
+			sourceIndexes = append(sourceIndexes, i)
+		}
+	}
+
+	keyIndexes, err := columnIndexes(columns, opts.KeyColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	hashIndexes := make([]int, len(columns))
+	for i := range columns {
+		hashIndexes[i] = i
+	}
+	if len(opts.HashColumns) > 0 {
+		if hashIndexes, err = columnIndexes(columns, opts.HashColumns); err != nil {
+			return nil, err
+		}
+	}
+
+	insertColumns := append(append([]string{}, columns...), opts.HashColumn)
+	if _, err := dst.quoteColumns(insertColumns); err != nil {
+		return nil, err
+	}
+	opts.BatchSize = dst.batchRows(opts.BatchSize, len(insertColumns))
+
+	summary := &SyncSummary{}
+	seen := make(map[string]bool)
+	var inserts [][]interface{}
+
+	flush := func() error {
+		if len(inserts) == 0 || opts.DryRun {
+			inserts = inserts[:0]
+			return nil
+		}
+		err := dst.insertBatch(dstTable, insertColumns, inserts)
+		inserts = inserts[:0]
+		return err
+	}
+
+	for it.Next() {
+		row := it.Values()
+		values := make([]interface{}, len(sourceIndexes), len(sourceIndexes)+1)
+		for i, idx := range sourceIndexes {
+			values[i] = row[idx]
+		}
+
+		key := make([]interface{}, len(keyIndexes))
+		for i, idx := range keyIndexes {
+			key[i] = values[idx]
+		}
+		if hasNull(key) {
+			return summary, fmt.Errorf("NULL key %v in the source query", key)
+		}
+
+		hashed := make([]interface{}, len(hashIndexes))
+		for i, idx := range hashIndexes {
+			hashed[i] = values[idx]
+		}
+		hash := RowHash(hashed)
+
+		k := syncKey(key)
+		if seen[k] {
+			return summary, fmt.Errorf("Duplicate key %v in the source query", key)
+		}
+		seen[k] = true
+
+		target, ok := existing[k]
+		delete(existing, k)
+
+		if !ok {
+			summary.Inserted++
+			inserts = append(inserts, append(values, hash))
+			if len(inserts) >= opts.BatchSize {
+				if err := flush(); err != nil {
+					return summary, err
+				}
+			}
+			continue
+		}
+
+		if target.hash == hash {
+			summary.Unchanged++
+			continue
+		}
+
+		summary.Updated++
+		if opts.DryRun {
+			continue
+		}
+		if err := dst.syncUpdate(dstTable, insertColumns, append(values, hash), opts.KeyColumns, target.key); err != nil {
+			return summary, err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return summary, err
+	}
+
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
+	if !opts.Delete {
+		return summary, nil
+	}
+
+	for _, target := range existing {
+		summary.Deleted++
+		if opts.DryRun {
+			continue
+		}
+
+		table, _ := dst.quoteName(dstTable)
+		where, args := dst.keyCondition(opts.KeyColumns, target.key, 0)
+		if _, err := dst.Exec("DELETE FROM "+table+" WHERE "+where, args...); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+func (m *Sql) loadSyncTargets(table string, keys []string, hashColumn string) (map[string]*syncTarget, error) {
+	quotedTable, err := m.quoteName(table)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := m.quoteColumns(append(append([]string{}, keys...), hashColumn))
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := m.Iterate("SELECT " + strings.Join(columns, ", ") + " FROM " + quotedTable)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	targets := make(map[string]*syncTarget)
+	for it.Next() {
+		values := it.Values()
+		key := values[:len(keys)]
+		if hasNull(key) {
+			return nil, fmt.Errorf("NULL key %v in %s", key, table)
+		}
+		targets[syncKey(key)] = &syncTarget{key: key, hash: hashText(values[len(keys)])}
+	}
+	return targets, it.Err()
+}
+
+// hasNull reports whether a key has a NULL part, which "k = NULL" would never
+// match.
+func hasNull(key []interface{}) bool {
+	for _, v := range key {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Sql) syncUpdate(table string, columns []string, values []interface{}, keys []string, key []interface{}) error {
+	quotedTable, err := m.quoteName(table)
+	if err != nil {
+		return err
+	}
+	quotedColumns, err := m.quoteColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	set := make([]string, len(quotedColumns))
+	for i, c := range quotedColumns {
+		set[i] = c + " = " + m.Dialect().Placeholder(i+1)
+	}
+
+	where, args := m.keyCondition(keys, key, len(values))
+	_, err = m.Exec("UPDATE "+quotedTable+" SET "+strings.Join(set, ", ")+" WHERE "+where, append(values, args...)...)
+	return err
+}
+
+// keyCondition builds "k1 = ? AND k2 = ?" for the given key values. offset is
+// the number of arguments that already precede the condition. keys must have
+// been validated.
+func (m *Sql) keyCondition(keys []string, values []interface{}, offset int) (string, []interface{}) {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = m.Dialect().QuoteIdent(k) + " = " + m.Dialect().Placeholder(offset+i+1)
+	}
+	return strings.Join(parts, " AND "), values
+}
+
+func columnIndexes(columns []string, names []string) ([]int, error) {
+	indexes := make([]int, len(names))
+	for i, n := range names {
+		indexes[i] = -1
+		for j, c := range columns {
+			if c == n {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return nil, errors.New("Column is not part of the source query: " + n)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,131 @@
+package picosql
+
+import "testing"
+
+func newSyncTables(t *testing.T) (*Sql, *Sql) {
+	src, dst := newTestDB(t), newTestDB(t)
+	mustExec(t, src,
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL)",
+		"INSERT INTO items VALUES (1, 'apple', 1.5), (2, 'pear', 2), (3, 'plum', 0.5)",
+	)
+	mustExec(t, dst, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL, current_hash TEXT)")
+	return src, dst
+}
+
+func TestSyncTable(t *testing.T) {
+	src, dst := newSyncTables(t)
+	opts := SyncOptions{KeyColumns: []string{"id"}, Delete: true}
+
+	s, err := SyncTable(src, "SELECT * FROM items", dst, "items", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != (SyncSummary{Inserted: 3}) {
+		t.Fatalf("first sync: %+v", *s)
+	}
+
+	s, err = SyncTable(src, "SELECT * FROM items", dst, "items", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != (SyncSummary{Unchanged: 3}) {
+		t.Fatalf("second sync: %+v", *s)
+	}
+
+	mustExec(t, src,
+		"UPDATE items SET price = 3 WHERE id = 2",
+		"DELETE FROM items WHERE id = 3",
+		"INSERT INTO items VALUES (4, 'fig', 4)",
+	)
+
+	dry := opts
+	dry.DryRun = true
+	s, err = SyncTable(src, "SELECT * FROM items", dst, "items", dry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SyncSummary{Inserted: 1, Updated: 1, Deleted: 1, Unchanged: 1}
+	if *s != want {
+		t.Fatalf("dry run: %+v, want %+v", *s, want)
+	}
+
+	s, err = SyncTable(src, "SELECT * FROM items", dst, "items", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != want {
+		t.Fatalf("third sync: %+v, want %+v", *s, want)
+	}
+
+	var rows []struct {
+		ID    int64   `db:"id"`
+		Price float64 `db:"price"`
+	}
+	if err := dst.Select(&rows, "SELECT id, price FROM items ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].ID != 1 || rows[1].ID != 2 || rows[1].Price != 3 || rows[2].ID != 4 {
+		t.Fatalf("destination rows: %+v", rows)
+	}
+}
+
+func TestSyncTableSourceHashColumn(t *testing.T) {
+	src, dst := newSyncTables(t)
+	mustExec(t, src,
+		"ALTER TABLE items ADD COLUMN current_hash TEXT",
+		"UPDATE items SET current_hash = 'stale'",
+	)
+	opts := SyncOptions{KeyColumns: []string{"id"}}
+
+	if _, err := SyncTable(src, "SELECT * FROM items", dst, "items", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := dst.Count("SELECT COUNT(*) FROM items WHERE current_hash = 'stale'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("%d rows kept the source hash", n)
+	}
+
+	s, err := SyncTable(src, "SELECT * FROM items", dst, "items", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != (SyncSummary{Unchanged: 3}) {
+		t.Fatalf("second sync: %+v", *s)
+	}
+}
+
+func TestSyncTableDuplicateKey(t *testing.T) {
+	src, dst := newSyncTables(t)
+	q := "SELECT * FROM items UNION ALL SELECT * FROM items WHERE id = 2"
+	if _, err := SyncTable(src, q, dst, "items", SyncOptions{KeyColumns: []string{"id"}}); err == nil {
+		t.Fatal("a repeated source key was synced")
+	}
+}
+
+func TestSyncTableNullKey(t *testing.T) {
+	src, dst := newSyncTables(t)
+	opts := SyncOptions{KeyColumns: []string{"name"}}
+	mustExec(t, src, "INSERT INTO items VALUES (5, NULL, 1)")
+	if _, err := SyncTable(src, "SELECT * FROM items", dst, "items", opts); err == nil {
+		t.Fatal("a NULL source key was synced")
+	}
+
+	mustExec(t, dst, "INSERT INTO items VALUES (9, NULL, 1, 'x')")
+	if _, err := SyncTable(src, "SELECT * FROM items WHERE name IS NOT NULL", dst, "items", opts); err == nil {
+		t.Fatal("a NULL destination key was synced")
+	}
+}
+
+func TestSyncTableInvalidNames(t *testing.T) {
+	src, dst := newSyncTables(t)
+	if _, err := SyncTable(src, "SELECT * FROM items", dst, "items; DROP TABLE items", SyncOptions{KeyColumns: []string{"id"}}); err == nil {
+		t.Fatal("an invalid table name was accepted")
+	}
+	if _, err := SyncTable(src, "SELECT * FROM items", dst, "items", SyncOptions{KeyColumns: []string{"id)"}}); err == nil {
+		t.Fatal("an invalid key column was accepted")
+	}
+}
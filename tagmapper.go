@@ -12,16 +12,24 @@ const (
 
 type tagMapper map[string]map[string]string
 
-var tagHelperLock sync.Mutex
+// tagHelperLock guards every tagMapper, including the shared one of the
+// generic helpers.
+var tagHelperLock sync.RWMutex
 
 func (tm tagMapper) get(target reflect.Type) map[string]string {
 	tn := target.Name()
-	if !tm.has(target) {
-		tagHelperLock.Lock()
 
-		tm.build(target)
+	tagHelperLock.RLock()
+	m, ok := tm[tn]
+	tagHelperLock.RUnlock()
+	if ok {
+		return m
+	}
 
-		tagHelperLock.Unlock()
+	tagHelperLock.Lock()
+	defer tagHelperLock.Unlock()
+	if !tm.has(target) {
+		tm.build(target)
 	}
 	return tm[tn]
 }
@@ -33,16 +41,8 @@ func (tm tagMapper) build(target reflect.Type) {
 	m := make(map[string]string)
 	for x := 0; x < t.NumField(); x++ {
 		f := t.Field(x)
-		fname := f.Name
-		tags := strings.Split(f.Tag.Get(tagPrefix), ",")
-		tag := fname
-		if len(tags) > 0 {
-			tag = strings.TrimSpace(tags[0])
-			if len(tag) == 0 {
-				tag = fname
-			}
-		}
-		m[tag] = fname
+		tag, _ := fieldTag(f)
+		m[tag] = f.Name
 	}
 
 	tm[tname] = m
@@ -53,3 +53,22 @@ func (tm tagMapper) has(target reflect.Type) bool {
 	_, ok := tm[name]
 	return ok
 }
+
+// fieldTag splits the db tag of a field into its column name, the field name
+// when blank, and options such as `db:"id,pk,auto"` or `db:"name,size=100"`.
+func fieldTag(f reflect.StructField) (string, map[string]string) {
+	tags := strings.Split(f.Tag.Get(tagPrefix), ",")
+	name := strings.TrimSpace(tags[0])
+	if len(name) == 0 {
+		name = f.Name
+	}
+
+	opts := make(map[string]string)
+	for _, o := range tags[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(o), "=")
+		if len(k) > 0 {
+			opts[strings.ToLower(k)] = strings.TrimSpace(v)
+		}
+	}
+	return name, opts
+}
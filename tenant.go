@@ -0,0 +1,264 @@
+package picosql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type TenantSpec struct {
+	Database string
+	// User defaults to Database.
+	User string
+	// Host the user may connect from, % when empty. MySQL only.
+	Host string
+	// Password of a new user, generated when empty. The password of an
+	// existing user is only changed with RotatePassword; give it here to
+	// have the DSN filled in and Migrations run for that user.
+	Password       string
+	RotatePassword bool
+	// Charset and Collation of the database, the server defaults when empty.
+	Charset   string
+	Collation string
+	// Privileges granted on the database, ALL PRIVILEGES when empty.
+	Privileges []string
+	// Migrations run in the new database through DSN.
+	Migrations     []*Migration
+	MigrateOptions MigrateOptions
+	// DSN of the tenant database, with {user}, {password} and {db}
+	// placeholders, e.g. "{user}:{password}@tcp(db:3306)/{db}". User and
+	// password are URL-encoded. Required to run Migrations.
+	DSN string
+}
+
+type TenantCredentials struct {
+	Database string
+	User     string
+	Host     string
+	// Password is empty when an existing user kept a password the caller
+	// did not give.
+	Password string
+	// PasswordChanged is true when the user was created or its password
+	// rotated.
+	PasswordChanged bool
+	// DSN is TenantSpec.DSN filled in, empty without one or without a
+	// password.
+	DSN string
+	// Created is false when the database already existed.
+	Created bool
+	// Migrated lists the migrations applied by this call.
+	Migrated []*Migration
+}
+
+var charsetName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ProvisionTenant creates the database and user of a tenant, grants the user
+// its privileges and runs the tenant migrations. It can run again safely: an
+// existing database is kept, and so are the credentials of an existing user
+// unless spec.RotatePassword is set.
+func (m *Sql) ProvisionTenant(spec TenantSpec) (*TenantCredentials, error) {
+	a, err := m.admin()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(spec.User) == 0 {
+		spec.User = spec.Database
+	}
+
+	if err := m.validateIdentifiers(spec.Database, spec.User); err != nil {
+		return nil, err
+	}
+	if len(spec.Database) == 0 {
+		return nil, errors.New("Tenant database is required")
+	}
+
+	for _, n := range []string{spec.Charset, spec.Collation} {
+		if len(n) > 0 && !charsetName.MatchString(n) {
+			return nil, fmt.Errorf("Invalid charset or collation %q", n)
+		}
+	}
+
+	if len(spec.Privileges) == 0 {
+		spec.Privileges = []string{"ALL PRIVILEGES"}
+	}
+	privileges, err := validatePrivileges(spec.Privileges)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(spec.Migrations) > 0 && len(spec.DSN) == 0 {
+		return nil, errors.New("Tenant DSN is required to run migrations")
+	}
+
+	userExists, err := m.accountExists(a, spec.User, spec.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	setPassword := !userExists || spec.RotatePassword
+	if setPassword && len(spec.Password) == 0 {
+		if spec.Password, err = generatePassword(24); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(spec.Migrations) > 0 && len(spec.Password) == 0 {
+		return nil, errors.New("Tenant user exists: its Password or RotatePassword is required to run migrations")
+	}
+
+	creds := &TenantCredentials{Database: spec.Database, User: spec.User, Host: spec.Host, Password: spec.Password, PasswordChanged: setPassword}
+
+	exists, err := m.DatabaseExists(spec.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if _, err := m.Exec(a.createDatabaseSQL(spec.Database, spec.Charset, spec.Collation)); err != nil {
+			return nil, err
+		}
+		creds.Created = true
+	}
+
+	if !userExists {
+		if _, err := m.Exec(a.createUserSQL(spec.User, spec.Host, spec.Password)); err != nil {
+			return creds, err
+		}
+	} else if spec.RotatePassword {
+		if _, err := m.Exec(a.setPasswordSQL(spec.User, spec.Host, spec.Password)); err != nil {
+			return creds, err
+		}
+	}
+
+	if _, err := m.Exec(a.grantSQL(privileges, spec.Database, "", spec.User, spec.Host, false)); err != nil {
+		return creds, err
+	}
+
+	if o, ok := a.(interface{ databaseOwnerSQL(db, user string) string }); ok && creds.Created {
+		if _, err := m.Exec(o.databaseOwnerSQL(spec.Database, spec.User)); err != nil {
+			return creds, err
+		}
+	}
+
+	if len(spec.DSN) > 0 && len(spec.Password) > 0 {
+		creds.DSN = tenantDSN(spec.DSN, spec.User, spec.Password, spec.Database)
+	}
+
+	if len(spec.Migrations) > 0 {
+		tenant, err := New(m.driver, creds.DSN)
+		if err != nil {
+			return creds, err
+		}
+		defer tenant.Close()
+
+		tenant.SetDialect(m.Dialect())
+		if creds.Migrated, err = tenant.Migrate(spec.Migrations, spec.MigrateOptions); err != nil {
+			return creds, err
+		}
+	}
+	return creds, nil
+}
+
+// tenantDSN fills in a DSN template, URL-encoding user and password so that
+// characters such as @, : or / do not end them early.
+func tenantDSN(template, user, password, db string) string {
+	userinfo := url.UserPassword(user, password).String()
+	sep := strings.IndexByte(userinfo, ':')
+	return strings.NewReplacer("{user}", userinfo[:sep], "{password}", userinfo[sep+1:], "{db}", db).Replace(template)
+}
+
+type DeprovisionOptions struct {
+	Database string
+	// Users to revoke and drop, the database name when empty.
+	Users []string
+	// Host of the users, % when empty. MySQL only.
+	Host string
+	// Confirm must repeat Database, as a guard against dropping the wrong
+	// one.
+	Confirm string
+	// KeepDatabase only revokes and drops the users. On PostgreSQL what they
+	// own, the database included, goes to the connected role, which has to be
+	// connected to Database for its tables to be covered.
+	KeepDatabase bool
+}
+
+// DeprovisionTenant revokes the privileges of the tenant users, drops them and
+// drops the tenant database. Missing users and databases are skipped.
+func (m *Sql) DeprovisionTenant(opts DeprovisionOptions) error {
+	a, err := m.admin()
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Database) == 0 || opts.Confirm != opts.Database {
+		return errors.New("Deprovisioning requires Confirm to repeat the database name")
+	}
+
+	users := opts.Users
+	if len(users) == 0 {
+		users = []string{opts.Database}
+	}
+
+	if err := m.validateIdentifiers(append([]string{opts.Database}, users...)...); err != nil {
+		return err
+	}
+
+	exists, err := m.DatabaseExists(opts.Database)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		userExists, err := m.accountExists(a, user, opts.Host)
+		if err != nil {
+			return err
+		}
+		if !userExists {
+			continue
+		}
+
+		if exists {
+			if _, err := m.Exec(a.revokeAllSQL(opts.Database, user, opts.Host)); err != nil {
+				return err
+			}
+		}
+
+		// PostgreSQL refuses to drop a role that still owns the database, so
+		// the role is dropped after it, or hands it over when it is kept.
+		if !opts.KeepDatabase && exists && m.Dialect().Name() == "postgres" {
+			continue
+		}
+
+		if r, ok := a.(interface{ reassignOwnedSQL(user string) []string }); ok && opts.KeepDatabase {
+			for _, q := range r.reassignOwnedSQL(user) {
+				if _, err := m.Exec(q); err != nil {
+					return err
+				}
+			}
+		}
+
+		if _, err := m.Exec(a.dropUserSQL(user, opts.Host)); err != nil {
+			return err
+		}
+	}
+
+	if opts.KeepDatabase || !exists {
+		return nil
+	}
+
+	if _, err := m.Exec(a.dropDatabaseSQL(opts.Database)); err != nil {
+		return err
+	}
+
+	if m.Dialect().Name() == "postgres" {
+		for _, user := range users {
+			if _, err := m.Exec(a.dropUserSQL(user, opts.Host)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package picosql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testAdminDialect manages fake databases and accounts kept in SQLite
+// tables, so that ProvisionTenant can run without a server.
+type testAdminDialect struct{ sqliteDialect }
+
+func (testAdminDialect) ListDatabasesQuery() (string, []interface{}) {
+	return "SELECT name FROM tenant_databases", nil
+}
+
+func (testAdminDialect) createDatabaseSQL(db, charset, collation string) string {
+	return fmt.Sprintf("INSERT INTO tenant_databases VALUES ('%s')", db)
+}
+
+func (testAdminDialect) dropDatabaseSQL(db string) string {
+	return fmt.Sprintf("DELETE FROM tenant_databases WHERE name = '%s'", db)
+}
+
+func (testAdminDialect) accountExistsQuery(user, host string) (string, []interface{}) {
+	return "SELECT 1 FROM tenant_accounts WHERE user = ?", []interface{}{user}
+}
+
+func (testAdminDialect) createUserSQL(user, host, password string) string {
+	return fmt.Sprintf("INSERT INTO tenant_accounts VALUES ('%s', '%s')", user, password)
+}
+
+func (testAdminDialect) setPasswordSQL(user, host, password string) string {
+	return fmt.Sprintf("UPDATE tenant_accounts SET password = '%s' WHERE user = '%s'", password, user)
+}
+
+func (testAdminDialect) dropUserSQL(user, host string) string {
+	return fmt.Sprintf("DELETE FROM tenant_accounts WHERE user = '%s'", user)
+}
+
+func (testAdminDialect) grantSQL(privileges []string, db, table, user, host string, grantOption bool) string {
+	return "SELECT 1"
+}
+
+func (testAdminDialect) revokeSQL(privileges []string, db, table, user, host string) string {
+	return "SELECT 1"
+}
+
+func (testAdminDialect) revokeAllSQL(db, user, host string) string { return "SELECT 1" }
+
+func (testAdminDialect) readOnlySQL(db, user, host string) []string { return nil }
+
+func (testAdminDialect) grantsQuery(user, host string) (string, []interface{}) {
+	return "SELECT '' WHERE 0", nil
+}
+
+func newTenantAdmin(t *testing.T) *Sql {
+	m := newTestDB(t)
+	mustExec(t, m,
+		"CREATE TABLE tenant_databases (name TEXT)",
+		"CREATE TABLE tenant_accounts (user TEXT, password TEXT)",
+	)
+	m.SetDialect(testAdminDialect{})
+	return m
+}
+
+func tenantPassword(t *testing.T, m *Sql, user string) string {
+	t.Helper()
+	var password string
+	if err := m.QueryRow("SELECT password FROM tenant_accounts WHERE user = ?", user).Scan(&password); err != nil {
+		t.Fatal(err)
+	}
+	return password
+}
+
+func TestProvisionTenant(t *testing.T) {
+	m := newTenantAdmin(t)
+	spec := TenantSpec{Database: "acme", DSN: "{user}:{password}@tcp(db)/{db}"}
+
+	creds, err := m.ProvisionTenant(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !creds.Created || !creds.PasswordChanged || len(creds.Password) != 24 || creds.User != "acme" {
+		t.Fatalf("first run = %+v", creds)
+	}
+	if want := "acme:" + creds.Password + "@tcp(db)/acme"; creds.DSN != want {
+		t.Errorf("DSN = %q, want %q", creds.DSN, want)
+	}
+	password := creds.Password
+
+	creds, err = m.ProvisionTenant(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Created || creds.PasswordChanged || len(creds.Password) > 0 || len(creds.DSN) > 0 {
+		t.Errorf("second run = %+v", creds)
+	}
+	if got := tenantPassword(t, m, "acme"); got != password {
+		t.Errorf("password changed to %q without RotatePassword", got)
+	}
+
+	spec.Migrations = []*Migration{{Version: 1, Name: "init", Up: "CREATE TABLE t (id INTEGER)"}}
+	if _, err := m.ProvisionTenant(spec); err == nil {
+		t.Error("expected an error running migrations without the password")
+	}
+	spec.Migrations = nil
+
+	spec.RotatePassword = true
+	spec.Password = "p@ss:w/rd"
+	creds, err = m.ProvisionTenant(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !creds.PasswordChanged || tenantPassword(t, m, "acme") != spec.Password {
+		t.Errorf("rotated run = %+v", creds)
+	}
+	if want := "acme:p%40ss%3Aw%2Frd@tcp(db)/acme"; creds.DSN != want {
+		t.Errorf("DSN = %q, want %q", creds.DSN, want)
+	}
+}
+
+func TestProvisionTenantGuards(t *testing.T) {
+	if _, err := newTestDB(t).ProvisionTenant(TenantSpec{Database: "acme"}); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("sqlite error = %v", err)
+	}
+
+	m := newTenantAdmin(t)
+	for _, spec := range []TenantSpec{
+		{},
+		{Database: "a;b"},
+		{Database: "acme", Charset: "utf8'"},
+		{Database: "acme", Privileges: []string{"DROP;"}},
+		{Database: "acme", Migrations: []*Migration{{Version: 1}}},
+	} {
+		if _, err := m.ProvisionTenant(spec); err == nil {
+			t.Errorf("ProvisionTenant(%+v) accepted an invalid spec", spec)
+		}
+	}
+
+	if err := m.DeprovisionTenant(DeprovisionOptions{Database: "acme", Confirm: "other"}); err == nil {
+		t.Error("DeprovisionTenant dropped a database without confirmation")
+	}
+}
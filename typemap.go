@@ -0,0 +1,178 @@
+package picosql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialectTypes spells one type for each built-in dialect. {n} is replaced by
+// the length, {p} and {s} by the precision and scale.
+type dialectTypes struct {
+	mysql, postgres, sqlite, sqlserver string
+}
+
+func (t dialectTypes) get(dialect string) string {
+	switch dialect {
+	case "postgres":
+		return t.postgres
+	case "sqlite":
+		return t.sqlite
+	case "sqlserver":
+		return t.sqlserver
+	}
+	return t.mysql
+}
+
+// dialectLimits holds a per dialect maximum, zero for none.
+type dialectLimits struct {
+	mysql, postgres, sqlite, sqlserver int
+}
+
+func (l dialectLimits) get(dialect string) int {
+	switch dialect {
+	case "postgres":
+		return l.postgres
+	case "sqlite":
+		return l.sqlite
+	case "sqlserver":
+		return l.sqlserver
+	}
+	return l.mysql
+}
+
+type typeMapping struct {
+	// names are the DBType values, as reported by the drivers of every
+	// engine, mapped by this row.
+	names []string
+	types dialectTypes
+	// defaultLength is used for {n} when the column has no length.
+	defaultLength int
+	// maxLength is the longest {n} a dialect takes; longer columns use the
+	// long row instead.
+	maxLength dialectLimits
+	long      string
+	// maxPrecision is the largest {p} a dialect takes.
+	maxPrecision dialectLimits
+	// unsigned is the wider row that holds the unsigned values of an
+	// integer type where the dialect has no UNSIGNED modifier.
+	unsigned string
+}
+
+var typeMappings = []*typeMapping{
+	{names: []string{"BOOL", "BOOLEAN", "BIT"}, types: dialectTypes{"TINYINT(1)", "BOOLEAN", "INTEGER", "BIT"}},
+	{names: []string{"BIT VARYING", "VARBIT"}, types: dialectTypes{"BIT({n})", "BIT VARYING({n})", "INTEGER", "VARBINARY(8)"}, defaultLength: 64, maxLength: dialectLimits{64, 0, 64, 64}, long: "VARBINARY"},
+	{names: []string{"TINYINT", "INT1"}, types: dialectTypes{"TINYINT", "SMALLINT", "INTEGER", "SMALLINT"}, unsigned: "SMALLINT"},
+	{names: []string{"SMALLINT", "INT2", "SMALLSERIAL", "YEAR"}, types: dialectTypes{"SMALLINT", "SMALLINT", "INTEGER", "SMALLINT"}, unsigned: "INT"},
+	{names: []string{"INT", "INTEGER", "INT4", "MEDIUMINT", "SERIAL"}, types: dialectTypes{"INT", "INTEGER", "INTEGER", "INT"}, unsigned: "BIGINT"},
+	{names: []string{"BIGINT", "INT8", "BIGSERIAL"}, types: dialectTypes{"BIGINT", "BIGINT", "INTEGER", "BIGINT"}, unsigned: "UNSIGNED BIGINT"},
+	{names: []string{"UNSIGNED BIGINT"}, types: dialectTypes{"BIGINT UNSIGNED", "NUMERIC(20,0)", "NUMERIC", "DECIMAL(20,0)"}},
+	{names: []string{"DECIMAL", "NUMERIC", "NEWDECIMAL"}, types: dialectTypes{"DECIMAL({p},{s})", "NUMERIC({p},{s})", "NUMERIC({p},{s})", "DECIMAL({p},{s})"}, maxPrecision: dialectLimits{65, 1000, 0, 38}},
+	{names: []string{"MONEY", "SMALLMONEY"}, types: dialectTypes{"DECIMAL(19,4)", "MONEY", "NUMERIC(19,4)", "MONEY"}},
+	{names: []string{"FLOAT", "FLOAT4", "REAL"}, types: dialectTypes{"FLOAT", "REAL", "REAL", "REAL"}},
+	{names: []string{"DOUBLE", "FLOAT8", "DOUBLE PRECISION"}, types: dialectTypes{"DOUBLE", "DOUBLE PRECISION", "REAL", "FLOAT"}},
+	{names: []string{"CHAR", "NCHAR", "BPCHAR", "CHARACTER"}, types: dialectTypes{"CHAR({n})", "CHAR({n})", "TEXT", "NCHAR({n})"}, maxLength: dialectLimits{255, 10485760, 0, 4000}, long: "VARCHAR"},
+	{names: []string{"VARCHAR", "NVARCHAR", "VARCHAR2", "CHARACTER VARYING", "STRING", "ENUM", "SET"}, types: dialectTypes{"VARCHAR({n})", "VARCHAR({n})", "TEXT", "NVARCHAR({n})"}, defaultLength: 100, maxLength: dialectLimits{16383, 10485760, 0, 4000}, long: "LONGTEXT"},
+	{names: []string{"TEXT", "TINYTEXT"}, types: dialectTypes{"TEXT", "TEXT", "TEXT", "NVARCHAR(MAX)"}},
+	{names: []string{"MEDIUMTEXT"}, types: dialectTypes{"MEDIUMTEXT", "TEXT", "TEXT", "NVARCHAR(MAX)"}},
+	{names: []string{"LONGTEXT", "NTEXT", "CLOB", "CITEXT"}, types: dialectTypes{"LONGTEXT", "TEXT", "TEXT", "NVARCHAR(MAX)"}},
+	{names: []string{"JSON", "JSONB"}, types: dialectTypes{"JSON", "JSONB", "TEXT", "NVARCHAR(MAX)"}},
+	{names: []string{"UUID", "UNIQUEIDENTIFIER"}, types: dialectTypes{"CHAR(36)", "UUID", "TEXT", "UNIQUEIDENTIFIER"}},
+	{names: []string{"DATE"}, types: dialectTypes{"DATE", "DATE", "DATE", "DATE"}},
+	{names: []string{"DATETIME", "DATETIME2", "SMALLDATETIME"}, types: dialectTypes{"DATETIME", "TIMESTAMP", "DATETIME", "DATETIME2"}},
+	{names: []string{"TIMESTAMP"}, types: dialectTypes{"TIMESTAMP", "TIMESTAMP", "DATETIME", "DATETIME2"}},
+	{names: []string{"TIMESTAMPTZ", "DATETIMEOFFSET"}, types: dialectTypes{"DATETIME", "TIMESTAMPTZ", "DATETIME", "DATETIMEOFFSET"}},
+	{names: []string{"TIME", "TIMETZ"}, types: dialectTypes{"TIME", "TIME", "TEXT", "TIME"}},
+	{names: []string{"BINARY"}, types: dialectTypes{"BINARY({n})", "BYTEA", "BLOB", "BINARY({n})"}, maxLength: dialectLimits{255, 0, 0, 8000}, long: "VARBINARY"},
+	{names: []string{"VARBINARY"}, types: dialectTypes{"VARBINARY({n})", "BYTEA", "BLOB", "VARBINARY({n})"}, defaultLength: 255, maxLength: dialectLimits{65535, 0, 0, 8000}, long: "LONGBLOB"},
+	{names: []string{"BLOB", "TINYBLOB"}, types: dialectTypes{"BLOB", "BYTEA", "BLOB", "VARBINARY(MAX)"}},
+	{names: []string{"MEDIUMBLOB"}, types: dialectTypes{"MEDIUMBLOB", "BYTEA", "BLOB", "VARBINARY(MAX)"}},
+	{names: []string{"LONGBLOB", "BYTEA", "IMAGE"}, types: dialectTypes{"LONGBLOB", "BYTEA", "BLOB", "VARBINARY(MAX)"}},
+}
+
+var typeMappingsByName = func() map[string]*typeMapping {
+	byName := make(map[string]*typeMapping)
+	for _, tm := range typeMappings {
+		for _, n := range tm.names {
+			byName[n] = tm
+		}
+	}
+	return byName
+}()
+
+// sqlType returns the column type of t in dialect d, or an error when t can
+// not be mapped.
+func sqlType(d Dialect, t *ColumnTypeSimplified) (string, error) {
+	dbType := strings.Join(strings.Fields(strings.ToUpper(t.DBType)), " ")
+
+	unsigned := false
+	for _, affix := range []string{"UNSIGNED ", " UNSIGNED"} {
+		if strings.Contains(dbType, affix) {
+			unsigned = true
+			dbType = strings.Replace(dbType, affix, "", 1)
+		}
+	}
+
+	tm, ok := typeMappingsByName[dbType]
+	if !ok {
+		return "", fmt.Errorf("Type %s of column %s can not be mapped to %s", t.DBType, t.Name, d.Name())
+	}
+
+	// Only a single bit is a boolean.
+	if dbType == "BIT" && t.Length > 1 {
+		tm = typeMappingsByName["BIT VARYING"]
+	}
+
+	dialect := d.Name()
+	if unsigned && len(tm.unsigned) > 0 {
+		if dialect == "mysql" && tm.unsigned != "UNSIGNED BIGINT" {
+			return tm.types.mysql + " UNSIGNED", nil
+		}
+		tm = typeMappingsByName[tm.unsigned]
+	}
+
+	length := t.Length
+	if length <= 0 {
+		// A CHAR of unknown length is better off as a VARCHAR.
+		if tm.defaultLength == 0 && len(tm.long) > 0 && strings.Contains(tm.types.get(dialect), "{n}") {
+			tm = typeMappingsByName[tm.long]
+		}
+		length = tm.defaultLength
+	}
+
+	for max := tm.maxLength.get(dialect); max > 0 && length > max; max = tm.maxLength.get(dialect) {
+		tm = typeMappingsByName[tm.long]
+	}
+
+	precision, scale := t.Precison, t.Scale
+	if precision <= 0 {
+		precision, scale = 20, 5
+	}
+
+	if max := tm.maxPrecision.get(dialect); max > 0 && precision > max {
+		return "", fmt.Errorf("Precision %d of column %s exceeds the %s maximum of %d", precision, t.Name, dialect, max)
+	}
+
+	if scale > precision {
+		return "", fmt.Errorf("Scale %d of column %s exceeds its precision %d", scale, t.Name, precision)
+	}
+
+	return strings.NewReplacer(
+		"{n}", strconv.Itoa(length),
+		"{p}", strconv.Itoa(precision),
+		"{s}", strconv.Itoa(scale),
+	).Replace(tm.types.get(dialect)), nil
+}
+
+// columnDefinition builds the column clause shared by the built-in dialects.
+func columnDefinition(d Dialect, name string, t *ColumnTypeSimplified) (string, error) {
+	s, err := sqlType(d, t)
+	if err != nil {
+		return "", err
+	}
+
+	if t.IsNullable {
+		return d.QuoteIdent(name) + " " + s + " NULL", nil
+	}
+	return d.QuoteIdent(name) + " " + s + " NOT NULL", nil
+}
@@ -0,0 +1,90 @@
+package picosql
+
+import "testing"
+
+func TestSQLType(t *testing.T) {
+	tests := []struct {
+		d    Dialect
+		t    ColumnTypeSimplified
+		want string
+	}{
+		{MySQLDialect, ColumnTypeSimplified{DBType: "datetime"}, "DATETIME"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "DATETIME"}, "TIMESTAMP"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "DECIMAL", Precison: 12, Scale: 3}, "DECIMAL(12,3)"},
+		{SQLServerDialect, ColumnTypeSimplified{DBType: "DECIMAL"}, "DECIMAL(20,5)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "VARCHAR", Length: 40}, "VARCHAR(40)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "VARCHAR"}, "VARCHAR(100)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "VARCHAR", Length: 70000}, "LONGTEXT"},
+		{SQLServerDialect, ColumnTypeSimplified{DBType: "NVARCHAR", Length: 5000}, "NVARCHAR(MAX)"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "CHAR"}, "VARCHAR(100)"},
+		{SQLiteDialect, ColumnTypeSimplified{DBType: "JSON"}, "TEXT"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "int unsigned"}, "BIGINT"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "INT UNSIGNED"}, "INT UNSIGNED"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "UNSIGNED BIGINT"}, "NUMERIC(20,0)"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "UUID"}, "UUID"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "BIT"}, "TINYINT(1)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "BIT", Length: 1}, "TINYINT(1)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "BIT", Length: 12}, "BIT(12)"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "BIT", Length: 12}, "BIT VARYING(12)"},
+		{SQLServerDialect, ColumnTypeSimplified{DBType: "BIT", Length: 12}, "VARBINARY(8)"},
+		{PostgresDialect, ColumnTypeSimplified{DBType: "VARBIT", Length: 100}, "BIT VARYING(100)"},
+		{MySQLDialect, ColumnTypeSimplified{DBType: "VARBIT", Length: 100}, "VARBINARY(100)"},
+		{SQLiteDialect, ColumnTypeSimplified{DBType: "VARBIT", Length: 100}, "BLOB"},
+	}
+	for _, tt := range tests {
+		got, err := sqlType(tt.d, &tt.t)
+		if err != nil {
+			t.Errorf("%s %+v: %v", tt.d.Name(), tt.t, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %+v: got %s, want %s", tt.d.Name(), tt.t, got, tt.want)
+		}
+	}
+
+	for _, bad := range []ColumnTypeSimplified{
+		{DBType: "GEOMETRY"},
+		{DBType: ""},
+		{DBType: "DECIMAL", Precison: 70},
+		{DBType: "DECIMAL", Precison: 5, Scale: 6},
+	} {
+		if got, err := sqlType(MySQLDialect, &bad); err == nil {
+			t.Errorf("%+v mapped to %s", bad, got)
+		}
+	}
+}
+
+func TestColumnDefinition(t *testing.T) {
+	def, err := columnDefinition(PostgresDialect, "name", &ColumnTypeSimplified{DBType: "TEXT"})
+	if err != nil || def != `"name" TEXT NOT NULL` {
+		t.Errorf("got %s, %v", def, err)
+	}
+
+	def, err = columnDefinition(MySQLDialect, "name", &ColumnTypeSimplified{DBType: "TEXT", IsNullable: true})
+	if err != nil || def != "`name` TEXT NULL" {
+		t.Errorf("got %s, %v", def, err)
+	}
+}
+
+func TestCreateTableNullability(t *testing.T) {
+	src, dst := newTestDB(t), newTestDB(t)
+	mustExec(t, src, "CREATE TABLE items (id INTEGER NOT NULL, name TEXT NOT NULL, note TEXT)")
+
+	info, err := src.DescribeQuery("SELECT * FROM items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The SQLite driver does not report nullability, others do.
+	info.Simplified[1].IsNullable = false
+
+	if err := dst.CreateTable("items", info.Columns, info.Simplified, "id"); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := dst.IntrospectTable("", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Columns[0].IsNullable || ts.Columns[1].IsNullable || !ts.Columns[2].IsNullable || !ts.Columns[3].IsNullable {
+		t.Errorf("nullability = %v %v %v %v", ts.Columns[0].IsNullable, ts.Columns[1].IsNullable, ts.Columns[2].IsNullable, ts.Columns[3].IsNullable)
+	}
+}
@@ -0,0 +1,174 @@
+package picosql
+
+import "errors"
+
+// Grant is a privilege held by a user, on a database or on one of its tables.
+// Database is empty for server wide MySQL privileges.
+type Grant struct {
+	Database    string `db:"db"`
+	Table       string `db:"table_name"`
+	Privilege   string `db:"privilege"`
+	GrantOption bool   `db:"grant_option"`
+}
+
+type GrantOptions struct {
+	// Privileges such as SELECT or ALL PRIVILEGES.
+	Privileges []string
+	Database   string
+	// Table narrows the grant to one table of Database.
+	Table       string
+	GrantOption bool
+}
+
+// CreateUser creates user, allowed to connect from host (% when empty, MySQL
+// only). An existing account for the same host is left as it is.
+func (m *Sql) CreateUser(user, host, password string) error {
+	a, err := m.admin()
+	if err != nil {
+		return err
+	}
+	if err := m.validateIdentifiers(user); err != nil {
+		return err
+	}
+	if len(user) == 0 {
+		return errors.New("User name is required")
+	}
+	exists, err := m.accountExists(a, user, host)
+	if err != nil || exists {
+		return err
+	}
+	_, err = m.Exec(a.createUserSQL(user, host, password))
+	return err
+}
+
+func (m *Sql) DropUser(user, host string) error {
+	a, err := m.admin()
+	if err != nil {
+		return err
+	}
+	if err := m.validateIdentifiers(user); err != nil {
+		return err
+	}
+	_, err = m.Exec(a.dropUserSQL(user, host))
+	return err
+}
+
+func (m *Sql) SetPassword(user, host, password string) error {
+	a, err := m.admin()
+	if err != nil {
+		return err
+	}
+	if err := m.validateIdentifiers(user); err != nil {
+		return err
+	}
+	_, err = m.Exec(a.setPasswordSQL(user, host, password))
+	return err
+}
+
+// RotatePassword sets a generated password for user and returns it.
+func (m *Sql) RotatePassword(user, host string) (string, error) {
+	password, err := generatePassword(24)
+	if err != nil {
+		return "", err
+	}
+	if err := m.SetPassword(user, host, password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func (m *Sql) Grant(user, host string, opts GrantOptions) error {
+	a, privileges, err := m.grantArgs(user, opts)
+	if err != nil {
+		return err
+	}
+	_, err = m.Exec(a.grantSQL(privileges, opts.Database, opts.Table, user, host, opts.GrantOption))
+	return err
+}
+
+// Revoke takes the privileges of opts back from user. GrantOption is ignored.
+func (m *Sql) Revoke(user, host string, opts GrantOptions) error {
+	a, privileges, err := m.grantArgs(user, opts)
+	if err != nil {
+		return err
+	}
+	_, err = m.Exec(a.revokeSQL(privileges, opts.Database, opts.Table, user, host))
+	return err
+}
+
+func (m *Sql) grantArgs(user string, opts GrantOptions) (adminDialect, []string, error) {
+	a, err := m.admin()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(opts.Database) == 0 {
+		return nil, nil, errors.New("Grant database is required")
+	}
+	if err := m.validateIdentifiers(user, opts.Database, opts.Table); err != nil {
+		return nil, nil, err
+	}
+	privileges, err := validatePrivileges(opts.Privileges)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, privileges, nil
+}
+
+// ListGrants returns the privileges of user on databases and tables. On
+// PostgreSQL table privileges are those of the connected database.
+func (m *Sql) ListGrants(user, host string) ([]*Grant, error) {
+	a, err := m.admin()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.validateIdentifiers(user); err != nil {
+		return nil, err
+	}
+
+	q, args := a.grantsQuery(user, host)
+	var grants []*Grant
+	if err := m.Select(&grants, q, args...); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// CreateReadOnlyUser creates user with read access to the tables of db and
+// returns its password, generated when empty. An existing user gets the new
+// password. On PostgreSQL m has to be connected to db.
+func (m *Sql) CreateReadOnlyUser(user, host, password, db string) (string, error) {
+	a, err := m.admin()
+	if err != nil {
+		return "", err
+	}
+	if err := m.validateIdentifiers(db, user); err != nil {
+		return "", err
+	}
+
+	if len(password) == 0 {
+		if password, err = generatePassword(24); err != nil {
+			return "", err
+		}
+	}
+
+	exists, err := m.accountExists(a, user, host)
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		err = m.SetPassword(user, host, password)
+	} else {
+		err = m.CreateUser(user, host, password)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, q := range a.readOnlySQL(db, user, host) {
+		if _, err := m.Exec(q); err != nil {
+			return "", err
+		}
+	}
+	return password, nil
+}
@@ -0,0 +1,100 @@
+package picosql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdminSQL(t *testing.T) {
+	my, pg := mysqlDialect{}, postgresDialect{}
+	tests := []struct {
+		got, want string
+	}{
+		{my.createDatabaseSQL("acme", "utf8mb4", "utf8mb4_bin"), "CREATE DATABASE IF NOT EXISTS `acme` CHARACTER SET utf8mb4 COLLATE utf8mb4_bin"},
+		{my.createUserSQL("bob", "", "it's"), "CREATE USER IF NOT EXISTS 'bob'@'%' IDENTIFIED BY 'it''s'"},
+		{my.setPasswordSQL("bob", "10.0.%", "pw"), "ALTER USER 'bob'@'10.0.%' IDENTIFIED BY 'pw'"},
+		{my.grantSQL([]string{"SELECT", "INSERT"}, "acme", "", "bob", "", true), "GRANT SELECT, INSERT ON `acme`.* TO 'bob'@'%' WITH GRANT OPTION"},
+		{my.revokeSQL([]string{"DELETE"}, "acme", "orders", "bob", ""), "REVOKE DELETE ON `acme`.`orders` FROM 'bob'@'%'"},
+		{pg.createDatabaseSQL("acme", "UTF8", ""), `CREATE DATABASE "acme" TEMPLATE template0 ENCODING 'UTF8'`},
+		{pg.createUserSQL("bob", "ignored", "pw"), `CREATE ROLE "bob" LOGIN PASSWORD 'pw'`},
+		{pg.grantSQL([]string{"SELECT"}, "acme", "orders", "bob", "", false), `GRANT SELECT ON TABLE "orders" TO "bob"`},
+		{pg.revokeAllSQL("acme", "bob", ""), `REVOKE ALL PRIVILEGES ON DATABASE "acme" FROM "bob"`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got  %s\nwant %s", tt.got, tt.want)
+		}
+	}
+
+	if q, args := my.accountExistsQuery("bob", ""); !strings.Contains(q, "Host = ?") || !reflect.DeepEqual(args, []interface{}{"bob", "%"}) {
+		t.Errorf("accountExistsQuery = %s %v", q, args)
+	}
+}
+
+func TestValidatePrivileges(t *testing.T) {
+	got, err := validatePrivileges([]string{"select", " all   privileges "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"SELECT", "ALL PRIVILEGES"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("privileges = %q, want %q", got, want)
+	}
+
+	for _, p := range [][]string{nil, {"SELECT; DROP TABLE t"}, {"INSERT(id)"}, {""}} {
+		if _, err := validatePrivileges(p); err == nil {
+			t.Errorf("validatePrivileges(%q) accepted invalid privileges", p)
+		}
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	a, err := generatePassword(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generatePassword(24)
+	if len(a) != 24 || a == b {
+		t.Errorf("passwords %q and %q", a, b)
+	}
+	if strings.Trim(a, passwordAlphabet) != "" {
+		t.Errorf("password %q has characters outside the alphabet", a)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	if err := newTestDB(t).CreateUser("bob", "", "pw"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("sqlite error = %v", err)
+	}
+
+	m := newTenantAdmin(t)
+	if err := m.CreateUser("bob", "", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateUser("bob", "", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tenantPassword(t, m, "bob"); got != "first" {
+		t.Errorf("CreateUser changed the password of an existing user to %q", got)
+	}
+
+	password, err := m.RotatePassword("bob", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tenantPassword(t, m, "bob"); got != password || got == "first" {
+		t.Errorf("password = %q, rotated to %q", got, password)
+	}
+
+	for _, err := range []error{
+		m.CreateUser("", "", "pw"),
+		m.CreateUser("bob;", "", "pw"),
+		m.Grant("bob", "", GrantOptions{Privileges: []string{"SELECT"}}),
+		m.Grant("bob", "", GrantOptions{Privileges: []string{"SELECT"}, Database: "acme", Table: "a'b"}),
+		m.Revoke("bob", "", GrantOptions{Database: "acme"}),
+	} {
+		if err == nil {
+			t.Error("expected an error for invalid arguments")
+		}
+	}
+}